@@ -1,7 +1,11 @@
 package assembler
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -165,7 +169,7 @@ func TestDirectives(t *testing.T) {
 			}
 
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expected, asm.output)
+			assert.Equal(t, tt.expected, asm.GetOutput())
 		})
 	}
 }
@@ -216,3 +220,1045 @@ func TestSymbols(t *testing.T) {
 		})
 	}
 }
+
+func TestPageCrossWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+		warnings []string
+	}{
+		{
+			name: "branch across page",
+			input: `
+				.org $10FA
+			loop:
+				NOP
+				NOP
+				NOP
+				NOP
+				BNE loop`,
+			expected: []byte{0xEA, 0xEA, 0xEA, 0xEA, 0xD0, 0xFA},
+			warnings: []string{"line 8: BNE to $10FA crosses a page boundary (+1 cycle when taken)"},
+		},
+		{
+			name: "branch within page",
+			input: `
+				.org $1000
+			loop:
+				NOP
+				BNE loop`,
+			expected: []byte{0xEA, 0xD0, 0xFD},
+		},
+		{
+			name: "word table straddling page",
+			input: `
+				.org $10FE
+				.word $1234, $5678`,
+			expected: []byte{0x34, 0x12, 0x78, 0x56},
+			warnings: []string{"line 3: word table $10FE-$1101 straddles a page boundary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			asm.WarnPageCross = true
+			err := asm.Assemble(tt.input)
+
+			assert.NoError(t, err)
+			result := asm.Result()
+			assert.Equal(t, tt.expected, result.Output)
+			assert.Equal(t, tt.warnings, result.Warnings)
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble(`
+		.segment "DATA"
+		.org $1010
+	table:
+		.byte $AA, $BB
+		.code
+		.org $1000
+	start:
+		LDA table
+		.data
+	more:
+		.byte $CC
+		.code
+		JMP start`)
+	assert.NoError(t, err)
+
+	segments := asm.Segments()
+	if assert.Len(t, segments, 2) {
+		assert.Equal(t, Segment{Name: CodeSegment, Origin: 0x1000, Data: []byte{
+			0xAD, 0x10, 0x10, // LDA table
+			0x4C, 0x00, 0x10, // JMP start
+		}}, segments[0])
+		assert.Equal(t, Segment{Name: DataSegment, Origin: 0x1010, Data: []byte{0xAA, 0xBB, 0xCC}}, segments[1])
+	}
+
+	assert.Equal(t, uint16(0x1012), asm.symbols["more"].Value)
+	assert.Equal(t, []byte{
+		0xAD, 0x10, 0x10, 0x4C, 0x00, 0x10,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xAA, 0xBB, 0xCC,
+	}, asm.GetOutput())
+}
+
+func TestForwardZeroPageConstants(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+	}{
+		{
+			name: "forward zero page constant in ,X mode",
+			input: `
+				.org $1000
+				LDA ZP,X
+				JMP end
+			end:
+				RTS
+			ZP = $10`,
+			expected: []byte{0xB5, 0x10, 0x4C, 0x05, 0x10, 0x60},
+		},
+		{
+			name: "forward zero page constant in ,Y mode",
+			input: `
+				LDX PTR,Y
+				STX PTR
+			PTR = $FB`,
+			expected: []byte{0xB6, 0xFB, 0x86, 0xFB},
+		},
+		{
+			name: "forward absolute label keeps absolute sizing",
+			input: `
+				.org $1000
+				LDA table,X
+				RTS
+			table:
+				.byte $01`,
+			expected: []byte{0xBD, 0x04, 0x10, 0x60, 0x01},
+		},
+		{
+			name: "constant defined before use",
+			input: `
+			SCREEN = $0400
+				STA SCREEN,X`,
+			expected: []byte{0x9D, 0x00, 0x04},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			err := asm.Assemble(tt.input)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, asm.GetOutput())
+		})
+	}
+}
+
+func TestByteTruncation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+		warnings []string
+	}{
+		{
+			name:     "16-bit value truncated with warning",
+			input:    `.byte $1234`,
+			expected: []byte{0x34},
+			warnings: []string{"line 1: .byte value $1234 truncated to $34"},
+		},
+		{
+			name:     "explicit high byte",
+			input:    `.byte >$1234`,
+			expected: []byte{0x12},
+		},
+		{
+			name:     "explicit low byte",
+			input:    `.byte <$1234`,
+			expected: []byte{0x34},
+		},
+		{
+			name: "label high and low bytes",
+			input: `
+				.org $C000
+			start:
+				.byte <start, >start, start`,
+			expected: []byte{0x00, 0xC0, 0x00},
+			warnings: []string{"line 4: .byte value $C000 truncated to $00"},
+		},
+		{
+			name:     "8-bit values don't warn",
+			input:    `.byte $FF, 0, 255`,
+			expected: []byte{0xFF, 0x00, 0xFF},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			err := asm.Assemble(tt.input)
+
+			assert.NoError(t, err)
+			result := asm.Result()
+			assert.Equal(t, tt.expected, result.Output)
+			assert.Equal(t, tt.warnings, result.Warnings)
+		})
+	}
+}
+
+func TestCrossReferences(t *testing.T) {
+	input := `.org $1000
+loop:
+	DEX
+	BNE loop
+	JMP loop
+	RTS`
+
+	asm := NewAssembler()
+	assert.NoError(t, asm.Assemble(input))
+
+	refs := asm.CrossReferences()
+	assert.Equal(t, []int{2, 4, 5}, refs["loop"])
+
+	symbol, exists := asm.LookupSymbol("loop")
+	assert.True(t, exists)
+	assert.Equal(t, 2, symbol.Line, "definition line")
+	assert.Equal(t, uint16(0x1000), symbol.Value)
+}
+
+func TestCrossReferencesInDirectives(t *testing.T) {
+	input := `.org $1000
+	LDA #<table
+	RTS
+handler:
+	RTI
+table:
+	.word handler, end
+	.byte >handler
+end:
+size = end-table
+	.byte size`
+
+	asm := NewAssembler()
+	assert.NoError(t, asm.Assemble(input))
+
+	refs := asm.CrossReferences()
+	assert.Equal(t, []int{4, 7, 8}, refs["handler"], "used only from .word and .byte")
+	assert.Equal(t, []int{2, 6, 10}, refs["table"])
+	assert.Equal(t, []int{7, 9, 10}, refs["end"])
+	assert.Equal(t, []int{10, 11}, refs["size"])
+}
+
+func TestAssembleReader(t *testing.T) {
+	source := `.org $1000
+start:
+	LDA #$01
+	STA $0200
+	JMP start`
+
+	fromString := NewAssembler()
+	assert.NoError(t, fromString.Assemble(source))
+
+	fromReader := NewAssembler()
+	assert.NoError(t, fromReader.AssembleReader(strings.NewReader(source)))
+	assert.Equal(t, fromString.GetOutput(), fromReader.GetOutput())
+}
+
+func TestUnknownInstruction(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"typo with operand", "\tLDAA #$00"},
+		{"typo after label", "start: LDAA #$00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			err := asm.Assemble(tt.input)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "unknown instruction")
+		})
+	}
+}
+
+func TestExternalReferences(t *testing.T) {
+	input := `.org $1000
+	LDA #$01
+	JSR EXTERN
+	STA EXTERN,X
+	RTS`
+
+	asm := NewAssembler()
+	err := asm.Assemble(input)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined symbol: EXTERN")
+
+	asm = NewAssembler()
+	asm.AllowExternals = true
+	assert.NoError(t, asm.Assemble(input))
+
+	result := asm.Result()
+	assert.Equal(t, []byte{
+		0xA9, 0x01, // LDA #$01
+		0x20, 0x00, 0x00, // JSR EXTERN
+		0x9D, 0x00, 0x00, // STA EXTERN,X
+		0x60, // RTS
+	}, result.Output)
+	assert.Equal(t, []ExternalRef{
+		{Symbol: "EXTERN", Address: 0x1003, Size: 2},
+		{Symbol: "EXTERN", Address: 0x1006, Size: 2},
+	}, result.Externals)
+}
+
+func TestExternalImmediateAndData(t *testing.T) {
+	input := `.org $1000
+	LDA #EXTERN
+	LDX #<EXTERN
+	LDY #>EXTERN
+	.byte 1, EXTERN, >EXTERN
+	.word $1234, EXTERN`
+
+	for _, line := range []string{"\tLDA #UNDEF", "\tLDA #>UNDEF", "\t.byte UNDEF", "\t.word UNDEF"} {
+		asm := NewAssembler()
+		err := asm.Assemble(line)
+		if assert.Error(t, err, line) {
+			assert.Contains(t, err.Error(), "undefined symbol: UNDEF", line)
+		}
+	}
+
+	asm := NewAssembler()
+	asm.AllowExternals = true
+	assert.NoError(t, asm.Assemble(input))
+
+	result := asm.Result()
+	assert.Equal(t, []byte{
+		0xA9, 0x00, // LDA #EXTERN
+		0xA2, 0x00, // LDX #<EXTERN
+		0xA0, 0x00, // LDY #>EXTERN
+		0x01, 0x00, 0x00, // .byte 1, EXTERN, >EXTERN
+		0x34, 0x12, 0x00, 0x00, // .word $1234, EXTERN
+	}, result.Output)
+	assert.Equal(t, []ExternalRef{
+		{Symbol: "EXTERN", Address: 0x1001, Size: 1},
+		{Symbol: "EXTERN", Address: 0x1003, Size: 1},
+		{Symbol: "EXTERN", Address: 0x1005, Size: 1, High: true},
+		{Symbol: "EXTERN", Address: 0x1007, Size: 1},
+		{Symbol: "EXTERN", Address: 0x1008, Size: 1, High: true},
+		{Symbol: "EXTERN", Address: 0x100B, Size: 2},
+	}, result.Externals)
+
+	asm = NewAssembler()
+	asm.AllowExternals = true
+	err := asm.Assemble("\t.word EXTERN+1")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "external symbol EXTERN used in an expression")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	data := []byte{0x01, 0x02, 0xFF, 0x80, 'H', 'I'}
+
+	// Independent reference: bitwise CRC-16/CCITT-FALSE check value for
+	// "123456789" is $29B1.
+	assert.Equal(t, uint16(0x29B1), crc16([]byte("123456789")))
+
+	tests := []struct {
+		name    string
+		kind    string
+		trailer []byte
+	}{
+		{"sum8", "sum8", []byte{0x13}}, // $01+$02+$FF+$80+'H'+'I' = $213
+		{"crc16", "crc16", []byte{uint8(crc16(data)), uint8(crc16(data) >> 8)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `.org $C000
+start:
+	.byte $01, $02, $FF, $80, "HI"
+	.checksum start, $C005, ` + tt.kind
+
+			asm := NewAssembler()
+			assert.NoError(t, asm.Assemble(input))
+			assert.Equal(t, append(append([]byte{}, data...), tt.trailer...), asm.GetOutput())
+		})
+	}
+}
+
+func TestChecksumErrors(t *testing.T) {
+	tests := []string{
+		".org $C000\n.byte 1\n.checksum $C000, $C000",
+		".org $C000\n.byte 1\n.checksum $C000, $C000, md5",
+		".org $C000\n.byte 1\n.checksum $C000, $C001, sum8",
+	}
+	for _, input := range tests {
+		asm := NewAssembler()
+		assert.Error(t, asm.Assemble(input), input)
+	}
+}
+
+func TestCMOSInstructions(t *testing.T) {
+	input := "\tWAI\n\tSTP"
+
+	asm := NewAssembler()
+	err := asm.Assemble(input)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "65C02")
+
+	asm = NewAssembler()
+	asm.CMOS = true
+	assert.NoError(t, asm.Assemble(input))
+	assert.Equal(t, []byte{0xCB, 0xDB}, asm.GetOutput())
+}
+
+func TestCMOSAddressingModes(t *testing.T) {
+	input := `.org $1000
+start:
+	BRA start
+	STZ $12
+	STZ $12,X
+	STZ $1234
+	STZ $1234,X
+	LDA ($FB)
+	STA ($FD)
+	JMP ($2000,X)
+	JMP ($2000)
+	LDA ($FB,X)
+	INC
+	DEC A
+	BIT #$80
+	BIT $12,X
+	PHX
+	PLY
+	TSB $12
+	TRB $1234`
+
+	asm := NewAssembler()
+	asm.CMOS = true
+	if assert.NoError(t, asm.Assemble(input)) {
+		assert.Equal(t, []byte{
+			0x80, 0xFE,
+			0x64, 0x12,
+			0x74, 0x12,
+			0x9C, 0x34, 0x12,
+			0x9E, 0x34, 0x12,
+			0xB2, 0xFB,
+			0x92, 0xFD,
+			0x7C, 0x00, 0x20,
+			0x6C, 0x00, 0x20,
+			0xA1, 0xFB,
+			0x1A,
+			0x3A,
+			0x89, 0x80,
+			0x34, 0x12,
+			0xDA,
+			0x7A,
+			0x04, 0x12,
+			0x1C, 0x34, 0x12,
+		}, asm.GetOutput())
+	}
+
+	for _, line := range []string{"\tLDA ($FB)", "\tBIT #$80", "\tINC", "\tJMP ($2000,X)", "start:\n\tBRA start"} {
+		asm := NewAssembler()
+		assert.Error(t, asm.Assemble(line), "%s needs CMOS", line)
+	}
+}
+
+func TestBranchReport(t *testing.T) {
+	input := `.org $10F0
+back:
+	DEX
+	BNE back
+	BEQ ahead
+	.byte 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0
+ahead:
+	BCC back`
+
+	asm := NewAssembler()
+	assert.NoError(t, asm.Assemble(input))
+
+	result := asm.Result()
+	assert.Equal(t, []BranchInfo{
+		{Line: 4, Address: 0x10F1, Target: 0x10F0, Distance: -3, Headroom: 125},
+		{Line: 5, Address: 0x10F3, Target: 0x1103, Distance: 14, Headroom: 113, CrossesPage: true},
+		{Line: 8, Address: 0x1103, Target: 0x10F0, Distance: -21, Headroom: 107, CrossesPage: true},
+	}, result.Branches)
+	assert.Contains(t, result.BranchReport(), "line 5: $10F3 -> $1103  distance +14  headroom 113  crosses page")
+}
+
+func TestOutputFormats(t *testing.T) {
+	RegisterFormat("hex", FormatterFunc(func(result AsmResult) ([]byte, error) {
+		return []byte(fmt.Sprintf("% X", result.Output)), nil
+	}))
+	defer delete(formatters, "hex")
+
+	asm := NewAssembler()
+	assert.NoError(t, asm.Assemble("\tLDA #$01\n\tRTS"))
+
+	output, err := FormatOutput("hex", asm.Result())
+	assert.NoError(t, err)
+	assert.Equal(t, "A9 01 60", string(output))
+
+	output, err = FormatOutput("bin", asm.Result())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xA9, 0x01, 0x60}, output)
+
+	_, err = FormatOutput("nope", asm.Result())
+	assert.Error(t, err)
+	assert.Contains(t, FormatNames(), "hex")
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+	}{
+		{
+			name: "symbol plus offset",
+			input: `
+				.org $1000
+				LDA label+5
+			label:
+				RTS`,
+			expected: []byte{0xAD, 0x08, 0x10, 0x60},
+		},
+		{
+			name: "low and high byte",
+			input: `
+				.org $1234
+			label:
+				LDA #<label
+				LDX #>label
+				LDY #>label+$100`,
+			expected: []byte{0xA9, 0x34, 0xA2, 0x12, 0xA0, 0x13},
+		},
+		{
+			name: "precedence and parentheses",
+			input: `
+				LDA #2+3*4
+				LDA #(2+3)*4
+				LDA #20/3-1`,
+			expected: []byte{0xA9, 14, 0xA9, 20, 0xA9, 5},
+		},
+		{
+			name: "zero page after evaluation",
+			input: `
+			ptr = $F0
+				LDA ptr+1
+				LDA ptr*2,X`,
+			expected: []byte{0xA5, 0xF1, 0xBD, 0xE0, 0x01},
+		},
+		{
+			name: "forward zero page expression",
+			input: `
+				STA ptr+1
+			ptr = $80`,
+			expected: []byte{0x85, 0x81},
+		},
+		{
+			name: "directives",
+			input: `
+			base = $C000
+				.byte <(base+2), >base
+				.word base+$10`,
+			expected: []byte{0x02, 0xC0, 0x10, 0xC0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			if assert.NoError(t, asm.Assemble(tt.input)) {
+				assert.Equal(t, tt.expected, asm.GetOutput())
+			}
+		})
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"  LDA #10/0", "line 1: division by zero"},
+		{"  LDA #1\n  .byte 4/(2-2)", "line 2: division by zero"},
+		{"  LDA missing+1", "line 1: undefined symbol: missing"},
+		{"  LDA (1+2", "line 1: missing )"},
+	}
+	for _, tt := range tests {
+		asm := NewAssembler()
+		err := asm.Assemble(tt.input)
+		if assert.Error(t, err, tt.input) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+	}{
+		{
+			name: "immediate",
+			input: `
+			COLOR = 14
+				LDA #COLOR`,
+			expected: []byte{0xA9, 0x0E},
+		},
+		{
+			name: "absolute",
+			input: `
+			CHROUT = $FFD2
+				JSR CHROUT`,
+			expected: []byte{0x20, 0xD2, 0xFF},
+		},
+		{
+			name: "zero page",
+			input: `
+			PTR .equ $FB
+				LDA (PTR),Y
+				STA PTR`,
+			expected: []byte{0xB1, 0xFB, 0x85, 0xFB},
+		},
+		{
+			name: "data lists",
+			input: `
+			BORDER .EQU $D020
+			WIDTH = 40
+				.byte WIDTH, <BORDER
+				.word BORDER, WIDTH*25`,
+			expected: []byte{0x28, 0x20, 0x20, 0xD0, 0xE8, 0x03},
+		},
+		{
+			name: "same value redefined",
+			input: `
+			WIDTH = 40
+			WIDTH = 20+20
+				LDA #WIDTH`,
+			expected: []byte{0xA9, 0x28},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			if assert.NoError(t, asm.Assemble(tt.input)) {
+				assert.Equal(t, tt.expected, asm.GetOutput())
+			}
+		})
+	}
+}
+
+func TestConstantRedefinition(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble("WIDTH = 40\n  LDA #WIDTH\nWIDTH .equ 80\n")
+	if assert.Error(t, err) {
+		assert.Equal(t, "line 3: WIDTH redefined as $0050 (was $0028 on line 1)", err.Error())
+	}
+
+	asm = NewAssembler()
+	assert.Error(t, asm.Assemble("start:\n  NOP\nstart:\n  RTS\n"), "duplicate label at another address")
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, source string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(source), 0644))
+		return path
+	}
+
+	writeFile("lib/constants.asm", "CHROUT = $FFD2\n.include \"colors.asm\"\n")
+	writeFile("lib/colors.asm", "WHITE = 1\n")
+	main := writeFile("main.asm", `
+	.org $C000
+	.include "lib/constants.asm"
+	LDA #WHITE
+	JSR CHROUT
+	RTS
+`)
+
+	asm := NewAssembler()
+	if assert.NoError(t, asm.AssembleFile(main)) {
+		assert.Equal(t, []byte{0xA9, 0x01, 0x20, 0xD2, 0xFF, 0x60}, asm.GetOutput())
+	}
+
+	// Assemble resolves top-level includes against BaseDir
+	asm = NewAssembler()
+	asm.BaseDir = dir
+	if assert.NoError(t, asm.Assemble(".include \"lib/constants.asm\"\n  JMP CHROUT\n")) {
+		assert.Equal(t, []byte{0x4C, 0xD2, 0xFF}, asm.GetOutput())
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	self := filepath.Join(dir, "self.asm")
+	assert.NoError(t, os.WriteFile(self, []byte(".include \"self.asm\"\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.asm"), []byte(".include \"b.asm\"\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.asm"), []byte("NOP\n.include \"a.asm\"\n"), 0644))
+
+	asm := NewAssembler()
+	err := asm.AssembleFile(self)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "includes itself")
+	}
+
+	asm = NewAssembler()
+	err = asm.AssembleFile(filepath.Join(dir, "a.asm"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "recursive include")
+	}
+
+	asm = NewAssembler()
+	asm.BaseDir = dir
+	err = asm.Assemble("  NOP\n.include \"missing.asm\"\n")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: .include missing.asm")
+	}
+}
+
+func TestStringDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+	}{
+		{".byte keeps spaces and commas", `.byte "a, b", 0`, []byte{'a', ',', ' ', 'b', 0}},
+		{".text", `.text "Hi"`, []byte{'H', 'i'}},
+		{".asciiz", `.asciiz "OK"`, []byte{'O', 'K', 0}},
+		{".asciiz empty", `.asciiz ""`, []byte{0}},
+		{"escaped quote", `.asciiz "say \"hi\""`, []byte{'s', 'a', 'y', ' ', '"', 'h', 'i', '"', 0}},
+		{"escaped backslash", `.byte "\\"`, []byte{'\\'}},
+		{".petscii", `.petscii "Hello!", 13`, []byte{0xC8, 0x45, 0x4C, 0x4C, 0x4F, 0x21, 0x0D}},
+		{".screen", `.screen "Hi @1"`, []byte{0x48, 0x09, 0x20, 0x00, 0x31}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			if assert.NoError(t, asm.Assemble(tt.input)) {
+				assert.Equal(t, tt.expected, asm.GetOutput())
+			}
+		})
+	}
+}
+
+func TestStringDirectivesAdvancePC(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble(`
+		.org $1000
+	msg: .asciiz "hello"
+	after:
+		LDA msg`)
+	if assert.NoError(t, err) {
+		after, _ := asm.LookupSymbol("after")
+		assert.Equal(t, uint16(0x1006), after.Value)
+	}
+
+	assert.Error(t, NewAssembler().Assemble(`.asciiz "open`))
+}
+
+func TestListing(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble("; demo\n\t.org $C000\nstart:\tLDA #$01\n\tSTA $D020\n\t.byte 1,2,3,4\n\tJMP start\n")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	listing := asm.Listing()
+	if assert.Len(t, listing, 6) {
+		assert.Equal(t, ListEntry{Line: 1, Addr: 0x0000, Source: "; demo"}, listing[0])
+		assert.Equal(t, ListEntry{Line: 2, Addr: 0xC000, Source: "\t.org $C000"}, listing[1])
+		assert.Equal(t, ListEntry{Line: 3, Addr: 0xC000, Bytes: []byte{0xA9, 0x01}, Source: "start:\tLDA #$01"}, listing[2])
+		assert.Equal(t, ListEntry{Line: 4, Addr: 0xC002, Bytes: []byte{0x8D, 0x20, 0xD0}, Source: "\tSTA $D020"}, listing[3])
+		assert.Equal(t, ListEntry{Line: 5, Addr: 0xC005, Bytes: []byte{1, 2, 3, 4}, Source: "\t.byte 1,2,3,4"}, listing[4])
+		assert.Equal(t, ListEntry{Line: 6, Addr: 0xC009, Bytes: []byte{0x4C, 0x00, 0xC0}, Source: "\tJMP start"}, listing[5])
+	}
+
+	// The listed bytes are exactly the output image
+	var image []byte
+	for _, entry := range listing {
+		image = append(image, entry.Bytes...)
+	}
+	assert.Equal(t, asm.GetOutput(), image)
+}
+
+func TestErrorsAreCollected(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble(`	LDA #1
+	LDX (1+2
+	STA $0200
+	JMP nowhere
+	BNE 200
+	RTS`)
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"line 2: missing ) in expression (1+2",
+		"line 4: undefined symbol: nowhere",
+		"line 5: BNE at $0008 to $00C8 is out of range (+190 bytes, limit -128..+127); branch around a jump instead: BEQ skip / JMP $00C8 / skip:",
+	}, asm.Errors())
+	assert.Equal(t, strings.Join(asm.Errors(), "\n"), err.Error())
+
+	asm = NewAssembler()
+	assert.NoError(t, asm.Assemble("\tNOP\n"))
+	assert.Empty(t, asm.Errors())
+}
+
+func TestOrgBlocks(t *testing.T) {
+	t.Run("non-contiguous", func(t *testing.T) {
+		asm := NewAssembler()
+		err := asm.Assemble(`
+			.org $2000
+			.byte 1, 2
+			.org $2004
+			.byte 3`)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Segment{
+				{Name: CodeSegment, Origin: 0x2000, Data: []byte{1, 2}},
+				{Name: CodeSegment, Origin: 0x2004, Data: []byte{3}},
+			}, asm.Segments())
+			assert.Equal(t, []byte{1, 2, 0, 0, 3}, asm.GetOutput())
+		}
+	})
+
+	t.Run("backward overwrites", func(t *testing.T) {
+		asm := NewAssembler()
+		err := asm.Assemble(`
+			.org $1000
+			.byte 1, 2, 3, 4
+			.org $1001
+			.byte $AA
+			.org $0FFE
+			.byte $BB`)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Segment{
+				{Name: CodeSegment, Origin: 0x0FFE, Data: []byte{0xBB}},
+				{Name: CodeSegment, Origin: 0x1000, Data: []byte{1, 2, 3, 4}},
+				{Name: CodeSegment, Origin: 0x1001, Data: []byte{0xAA}},
+			}, asm.Segments())
+			assert.Equal(t, []byte{0xBB, 0, 1, 0xAA, 3, 4}, asm.GetOutput())
+		}
+	})
+
+	t.Run("origin before any code", func(t *testing.T) {
+		asm := NewAssembler()
+		if assert.NoError(t, asm.Assemble(".org $0801\n.org $C000\n\tRTS")) {
+			assert.Equal(t, []Segment{{Name: CodeSegment, Origin: 0xC000, Data: []byte{0x60}}}, asm.Segments())
+		}
+	})
+
+	t.Run("expression", func(t *testing.T) {
+		asm := NewAssembler()
+		err := asm.Assemble(`
+	base = $2000
+			.org base+2
+	start:
+			.byte 1
+			.org start+$10
+			.byte 2`)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Segment{
+				{Name: CodeSegment, Origin: 0x2002, Data: []byte{1}},
+				{Name: CodeSegment, Origin: 0x2012, Data: []byte{2}},
+			}, asm.Segments())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			input string
+			err   string
+		}{
+			{"\t.org UNDEFINED\n\tRTS\n", "line 1: undefined symbol: UNDEFINED"},
+			{"\t.org $10+\n\tRTS\n", "line 1: missing operand in expression $10+"},
+		}
+		for _, tt := range tests {
+			err := NewAssembler().Assemble(tt.input)
+			if assert.Error(t, err, tt.input) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		}
+	})
+}
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		start   uint16
+		table   uint16
+		padding int
+	}{
+		{0x1000, 0x1000, 0},
+		{0x1001, 0x1100, 255},
+		{0x1080, 0x1100, 128},
+		{0x10FF, 0x1100, 1},
+	}
+
+	for _, tt := range tests {
+		asm := NewAssembler()
+		err := asm.Assemble(fmt.Sprintf(".org $%04X\n.align 256\ntable: .byte $AA\n", tt.start))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		table, _ := asm.LookupSymbol("table")
+		assert.Equal(t, tt.table, table.Value, "start $%04X", tt.start)
+		output := asm.GetOutput()
+		assert.Len(t, output, tt.padding+1)
+		assert.Equal(t, uint8(0xAA), output[len(output)-1])
+	}
+
+	asm := NewAssembler()
+	if assert.NoError(t, asm.Assemble(".org $20FD\n\tNOP\n.align 4, $EA\n\tRTS\n")) {
+		assert.Equal(t, []byte{0xEA, 0xEA, 0xEA, 0x60}, asm.GetOutput())
+	}
+
+	assert.Error(t, NewAssembler().Assemble(".align 0"))
+	assert.Error(t, NewAssembler().Assemble(".align 16, $100"))
+}
+
+func TestRepeat(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble(`
+		.org $1000
+	squares:
+		.repeat 16, i
+		.byte i*i
+		.endrepeat
+	after:
+		.repeat 3
+		ASL A
+		.endrepeat
+		LDA squares+3`)
+	if assert.NoError(t, err) {
+		expected := make([]byte, 0, 16)
+		for i := 0; i < 16; i++ {
+			expected = append(expected, byte(i*i))
+		}
+		expected = append(expected, 0x0A, 0x0A, 0x0A, 0xAD, 0x03, 0x10)
+		assert.Equal(t, expected, asm.GetOutput())
+		after, _ := asm.LookupSymbol("after")
+		assert.Equal(t, uint16(0x1010), after.Value)
+	}
+
+	asm = NewAssembler()
+	err = asm.Assemble(`
+		.repeat 2, row
+		.repeat 3, col ; nested
+		.byte row*16+col
+		.endrepeat
+		.endrepeat
+		.repeat 0
+		.byte $FF
+		.endrepeat
+		.repeat later
+		NOP
+		.endrepeat
+	later = 2`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte{0x00, 0x01, 0x02, 0x10, 0x11, 0x12, 0xEA, 0xEA}, asm.GetOutput())
+	}
+}
+
+func TestRepeatErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"\tNOP\n\t.endrepeat\n", "line 2: .endrepeat without .repeat"},
+		{"\t.repeat 2\n\t.byte 1\n", "line 1: .repeat without .endrepeat"},
+		{"\t.repeat count\n\t.endrepeat\n", "line 1: undefined symbol: count"},
+	}
+	for _, tt := range tests {
+		err := NewAssembler().Assemble(tt.input)
+		if assert.Error(t, err, tt.input) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
+
+func TestBranchRangeError(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble(`
+		.org $1000
+	loop:
+		.repeat 200
+		NOP
+		.endrepeat
+		BCC loop`)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 7: BCC at $10C8 to loop ($1000) is out of range (-202 bytes")
+		assert.Contains(t, err.Error(), "BCS skip / JMP loop / skip:")
+	}
+}
+
+func TestCharacterLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+	}{
+		{"immediate", "\tLDA #'A'", []byte{0xA9, 0x41}},
+		{"byte list", "\t.byte 'H','i'", []byte{'H', 'i'}},
+		{"space and comma", "\t.byte ' ', ',', ';'", []byte{' ', ',', ';'}},
+		{"escapes", `	.byte '\n', '\'', '\\', '\0'`, []byte{0x0A, '\'', '\\', 0x00}},
+		{"screen code", "\tLDA #^'A'\n\tLDX #^'a'", []byte{0xA9, 0x41, 0xA2, 0x01}},
+		{"arithmetic", "\tCMP #'0'+9", []byte{0xC9, 0x39}},
+		{"string escapes", `	.byte "a\tb\r"`, []byte{'a', 0x09, 'b', 0x0D}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := NewAssembler()
+			if assert.NoError(t, asm.Assemble(tt.input)) {
+				assert.Equal(t, tt.expected, asm.GetOutput())
+			}
+		})
+	}
+
+	assert.Error(t, NewAssembler().Assemble("\tLDA #''"))
+	assert.Error(t, NewAssembler().Assemble("\tLDA #'AB'"))
+}
+
+func TestViceLabels(t *testing.T) {
+	asm := NewAssembler()
+	err := asm.Assemble(`
+	CHROUT = $FFD2
+	BORDER .equ $D020
+		.org $C000
+	start:
+		LDA #0
+	loop:
+		JSR CHROUT
+		.repeat 2, i
+		.byte i
+		.endrepeat
+		BNE loop
+	table:
+		.word start`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]uint16{"start": 0xC000, "loop": 0xC002, "table": 0xC009}, asm.SymbolMap())
+
+	var labels strings.Builder
+	assert.NoError(t, asm.WriteViceLabels(&labels))
+	assert.Equal(t, "al C:c000 .start\nal C:c002 .loop\nal C:c009 .table\n", labels.String())
+}
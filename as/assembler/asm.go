@@ -2,6 +2,9 @@ package assembler
 
 import (
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 )
 
 // Symbol represents a label or variable in the assembly
@@ -9,6 +12,8 @@ type Symbol struct {
 	Name      string
 	Value     uint16
 	IsDefined bool
+	IsLabel   bool // Defined as the PC, not by =, .equ or a .repeat counter
+	Line      int  // Source line of the definition
 }
 
 // Assembler holds the state of our assembler
@@ -16,8 +21,77 @@ type Assembler struct {
 	symbols     map[string]*Symbol
 	currentPass int
 	pc          uint16
+	line        int // Source line currently being processed
 	output      []byte
-	errors      []string
+	origin      uint16 // Address of the first byte in output
+
+	segment      string // Name of the active segment
+	segments     map[string]*segmentState
+	segmentOrder []string
+	errors       []lineError
+	warnings     []string
+	xrefs        map[string][]int // Symbol name to referencing source lines
+	listing      []ListEntry
+
+	// CMOS accepts the 65C02 instructions in the instruction set
+	CMOS bool
+
+	// BaseDir is the directory .include paths in the top-level source
+	// resolve against. AssembleFile sets it to the source file's directory.
+	BaseDir string
+	path    string
+
+	// AllowExternals assembles references to undefined symbols as zero
+	// placeholders and records them in AsmResult.Externals for a later link
+	// step, instead of failing.
+	AllowExternals bool
+	externals      []ExternalRef
+	branches       []BranchInfo
+
+	// WarnPageCross enables warnings for taken branches that cross a page
+	// (costing an extra cycle) and for .word tables straddling a page boundary.
+	WarnPageCross bool
+}
+
+// AsmResult collects the products of an assembly run
+type AsmResult struct {
+	Output    []byte
+	Warnings  []string      // Non-fatal diagnostics, prefixed with their line number
+	Externals []ExternalRef // Undefined symbols left for fixup when AllowExternals is set
+	Branches  []BranchInfo  // Every relative branch, in source order
+}
+
+// BranchInfo describes the reach of one assembled relative branch
+type BranchInfo struct {
+	Line        int
+	Address     uint16 // Address of the branch opcode
+	Target      uint16
+	Distance    int  // Signed offset from the following instruction
+	Headroom    int  // Bytes left before the offset leaves -128..127
+	CrossesPage bool // Taken branch costs an extra cycle
+}
+
+// BranchReport formats Branches as one line per branch
+func (r AsmResult) BranchReport() string {
+	var report strings.Builder
+	for _, b := range r.Branches {
+		page := ""
+		if b.CrossesPage {
+			page = "  crosses page"
+		}
+		report.WriteString(fmt.Sprintf("line %d: $%04X -> $%04X  distance %+d  headroom %d%s\n",
+			b.Line, b.Address, b.Target, b.Distance, b.Headroom, page))
+	}
+	return report.String()
+}
+
+// ExternalRef locates a zero-filled operand that must be patched with the
+// value of an undefined symbol
+type ExternalRef struct {
+	Symbol  string
+	Address uint16 // Address of the operand bytes
+	Size    int    // Operand width in bytes
+	High    bool   // A 1-byte operand takes the high byte of the symbol, as in #>name
 }
 
 // NewAssembler creates a new instance of our assembler
@@ -25,42 +99,142 @@ func NewAssembler() *Assembler {
 	return &Assembler{
 		symbols: make(map[string]*Symbol),
 		pc:      0,
-		errors:  make([]string, 0),
 	}
 }
 
+// maxSizingPasses bounds how often pass 1 is repeated waiting for symbol
+// values to settle
+const maxSizingPasses = 10
+
 // Helper functions for assembler
 func (a *Assembler) Assemble(source string) error {
+	a.symbols = make(map[string]*Symbol)
+	a.warnings = nil
+	a.externals = nil
+	a.branches = nil
+	a.xrefs = make(map[string][]int)
+	a.listing = nil
+
+	// First pass: collect symbols. Forward references are sized as absolute
+	// until their value is known, so repeat the pass until every symbol keeps
+	// its value; a forward zero-page symbol then shrinks its users to
+	// zero-page modes before any code is generated.
+	for sizingPass := 0; ; sizingPass++ {
+		previous := a.symbolValues()
+		if err := a.sizingPass(source); err != nil {
+			return err
+		}
+		if sizingPass > 0 && symbolValuesEqual(previous, a.symbolValues()) {
+			break
+		}
+		if sizingPass == maxSizingPasses {
+			return fmt.Errorf("symbol values did not stabilize after %d passes", maxSizingPasses)
+		}
+	}
+
+	// Second pass: generate code
+	a.currentPass = 2
+	a.resetSegments()
+	lexer := NewLexer(source)
+	parser := NewParser(lexer, a)
+
+	for {
+		line, err := parser.ParseLine()
+		if err != nil {
+			a.addError(a.line, err)
+			continue
+		}
+		if line == nil {
+			break
+		}
+		a.line = line.LineNum
+		a.recordReferences(line)
+
+		pc, segment, emitted := a.pc, a.segment, len(a.output)
+		if err := a.generateCode(line); err != nil {
+			a.addError(line.LineNum, err)
+			a.skipLine(line, pc, segment, emitted)
+			continue
+		}
+		a.listLine(line, pc, segment, emitted)
+	}
+
+	return a.errorResult()
+}
+
+// skipLine undoes any partial output of a line that failed on pass 2 and
+// moves the PC past it as sized on pass 1, so later addresses stay right.
+func (a *Assembler) skipLine(line *Line, pc uint16, segment string, emitted int) {
+	if a.segment != segment {
+		return
+	}
+	a.output = a.output[:emitted]
+	if inst, exists := instructionSet[line.Instruction]; exists {
+		if mode, exists := inst.mode(line.AddressMode, a.CMOS); exists {
+			a.pc = pc + uint16(mode.Size)
+		}
+	}
+}
+
+// AssembleReader assembles source read from r. Both passes walk the source,
+// so it is read in full before assembly starts.
+func (a *Assembler) AssembleReader(r io.Reader) error {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading source: %v", err)
+	}
+	return a.Assemble(string(source))
+}
+
+// sizingPass runs pass 1 over source, assigning symbol values and advancing
+// the PC by each instruction's size.
+func (a *Assembler) sizingPass(source string) error {
 	a.currentPass = 1
-	a.pc = 0
-	a.output = make([]byte, 0)
+	a.resetSegments()
 
-	// First pass: collect symbols
 	lexer := NewLexer(source)
 	parser := NewParser(lexer, a)
+	defined := make(map[string]bool)
+	a.errors = nil
 
 	for {
 		line, err := parser.ParseLine()
 		if err != nil {
-			return err
+			a.addError(a.line, err)
+			continue
 		}
 		if line == nil {
 			break
 		}
+		a.line = line.LineNum
 
-		// Handle labels
+		// Handle labels and constant assignments
 		if line.Label != "" {
+			value := a.pc
+			if line.Directive == "=" {
+				if value, err = parser.parseValue(line.Operand); err != nil {
+					a.addError(line.LineNum, err)
+					continue
+				}
+			}
+			if previous := a.symbols[line.Label]; defined[line.Label] && previous.Value != value {
+				a.addError(line.LineNum, fmt.Errorf("%s redefined as $%04X (was $%04X on line %d)",
+					line.Label, value, previous.Value, previous.Line))
+				continue
+			}
+			defined[line.Label] = true
 			a.symbols[line.Label] = &Symbol{
 				Name:      line.Label,
-				Value:     a.pc,
+				Value:     value,
 				IsDefined: true,
+				IsLabel:   line.Directive != "=",
+				Line:      line.LineNum,
 			}
-			//fmt.Printf("Label: %s PC: %x\n", line.Label, a.pc)
 		}
 		if line.Directive != "" {
 			if handler, exists := directiveHandlers[line.Directive]; exists {
 				if err := handler(a, line.Operand); err != nil {
-					return err
+					a.addError(line.LineNum, err)
 				}
 			}
 		}
@@ -68,36 +242,83 @@ func (a *Assembler) Assemble(source string) error {
 		// Update PC based on instruction size
 		if line.Instruction != "" {
 			if inst, exists := instructionSet[line.Instruction]; exists {
-				if mode, exists := inst.Modes[line.AddressMode]; exists {
+				if mode, exists := inst.mode(line.AddressMode, a.CMOS); exists {
 					a.pc += uint16(mode.Size)
-					//fmt.Printf("inst: %s Size: %x PC: %x\n", line.Instruction, mode.Size, a.pc)
 				}
 			}
 		}
 	}
+	return nil
+}
 
-	// Second pass: generate code
-	a.currentPass = 2
-	a.pc = 0
-	lexer = NewLexer(source)
-	parser = NewParser(lexer, a)
+// symbolValues captures the current value of every symbol
+func (a *Assembler) symbolValues() map[string]uint16 {
+	values := make(map[string]uint16, len(a.symbols))
+	for name, symbol := range a.symbols {
+		values[name] = symbol.Value
+	}
+	return values
+}
 
-	for {
-		line, err := parser.ParseLine()
-		if err != nil {
-			return err
-		}
-		if line == nil {
-			break
+func symbolValuesEqual(a, b map[string]uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, exists := b[name]; !exists || other != value {
+			return false
 		}
+	}
+	return true
+}
 
-		err = a.generateCode(line)
-		if err != nil {
-			return err
-		}
+// recordReferences notes the line defining a label and evaluates a constant
+// assignment, which pass 2 otherwise skips, so the symbols its value uses
+// are noted too. Every other operand notes its symbols through reference as
+// pass 2 evaluates it.
+func (a *Assembler) recordReferences(line *Line) {
+	if line.Label != "" {
+		a.addReference(line.Label, line.LineNum)
 	}
+	if line.Directive == "=" {
+		a.evaluate(line.Operand)
+	}
+}
 
-	return nil
+// reference notes that the current line uses name. Only pass 2 records
+// them, as the sizing passes read each line several times.
+func (a *Assembler) reference(name string) {
+	if a.currentPass == 2 {
+		a.addReference(name, a.line)
+	}
+}
+
+// addReference appends line to the cross-reference entry of name, once
+// however often the line's operands are evaluated
+func (a *Assembler) addReference(name string, line int) {
+	lines := a.xrefs[name]
+	if len(lines) > 0 && lines[len(lines)-1] == line {
+		return
+	}
+	a.xrefs[name] = append(lines, line)
+}
+
+// CrossReferences maps each symbol to the sorted source lines that define or
+// use it. LookupSymbol tells the definition apart from the uses.
+func (a *Assembler) CrossReferences() map[string][]int {
+	refs := make(map[string][]int, len(a.xrefs))
+	for name, lines := range a.xrefs {
+		sorted := append([]int(nil), lines...)
+		sort.Ints(sorted)
+		refs[name] = sorted
+	}
+	return refs
+}
+
+// LookupSymbol returns the symbol with the given name, if defined
+func (a *Assembler) LookupSymbol(name string) (*Symbol, bool) {
+	symbol, exists := a.symbols[name]
+	return symbol, exists
 }
 
 func (a *Assembler) generateCode(line *Line) error {
@@ -121,11 +342,19 @@ func (a *Assembler) generateCode(line *Line) error {
 	if !exists {
 		return fmt.Errorf("unknown instruction: %s", line.Instruction)
 	}
+	if inst.CMOS && !a.CMOS {
+		return fmt.Errorf("line %d: %s is a 65C02 instruction", line.LineNum, line.Instruction)
+	}
 
-	// If we have a symbol reference, get its final value
+	// If we have a symbolic operand, get its final value
+	external := false
 	if line.SymbolName != "" {
-		if symbol, exists := a.symbols[line.SymbolName]; exists {
-			line.Value = symbol.Value
+		expr, err := a.evaluate(line.SymbolName)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line.LineNum, err)
+		}
+		if expr.undefined == "" {
+			line.Value = expr.value
 			// Only try to optimize if the value is in zero page
 			if line.Value < 0x100 {
 				var optimizedMode AddressMode
@@ -139,19 +368,33 @@ func (a *Assembler) generateCode(line *Line) error {
 				}
 				// Only optimize if the instruction supports the zero page mode
 				if optimizedMode != line.AddressMode {
-					if _, supported := inst.Modes[optimizedMode]; supported {
+					if _, supported := inst.mode(optimizedMode, a.CMOS); supported {
 						line.AddressMode = optimizedMode
 					}
 				}
 			}
+		} else if !a.AllowExternals {
+			return fmt.Errorf("line %d: undefined symbol: %s", line.LineNum, expr.undefined)
+		} else {
+			external = true
 		}
 	}
 
-	mode, exists := inst.Modes[line.AddressMode]
+	mode, exists := inst.mode(line.AddressMode, a.CMOS)
 	if !exists {
 		return fmt.Errorf("invalid addressing mode for instruction %s", line.Instruction)
 	}
 
+	if external {
+		if mode.AddressMode == Relative {
+			return fmt.Errorf("line %d: branch to external symbol %s", line.LineNum, line.SymbolName)
+		}
+		line.Value = 0
+		if err := a.external(line.SymbolName, a.pc+1, int(mode.Size)-1); err != nil {
+			return err
+		}
+	}
+
 	// Output opcode
 	a.output = append(a.output, mode.Opcode)
 
@@ -163,7 +406,23 @@ func (a *Assembler) generateCode(line *Line) error {
 
 		// Check if branch is in range (-128 to +127)
 		if offset < -128 || offset > 127 {
-			return fmt.Errorf("branch target out of range (%d bytes)", offset)
+			return a.branchRangeError(line, offset)
+		}
+		headroom := 127 - int(offset)
+		if offset < 0 {
+			headroom = 128 + int(offset)
+		}
+		crossesPage := nextPC&0xFF00 != line.Value&0xFF00
+		a.branches = append(a.branches, BranchInfo{
+			Line:        line.LineNum,
+			Address:     a.pc,
+			Target:      line.Value,
+			Distance:    int(offset),
+			Headroom:    headroom,
+			CrossesPage: crossesPage,
+		})
+		if a.WarnPageCross && crossesPage {
+			a.warnf("%s to $%04X crosses a page boundary (+1 cycle when taken)", line.Instruction, line.Value)
 		}
 
 		// Output the offset.
@@ -183,6 +442,85 @@ func (a *Assembler) generateCode(line *Line) error {
 	return nil
 }
 
+// external records a fixup for an operand at address naming an undefined
+// symbol. The operand must be the symbol alone or, for a 1-byte operand, the
+// symbol behind a < or > byte selector.
+func (a *Assembler) external(operand string, address uint16, size int) error {
+	operand = strings.TrimSpace(operand)
+	high := false
+	if size == 1 && operand != "" && (operand[0] == '<' || operand[0] == '>') {
+		high = operand[0] == '>'
+		operand = strings.TrimSpace(operand[1:])
+	}
+	expr, err := a.evaluate(operand)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", a.line, err)
+	}
+	if expr.undefined != operand {
+		return fmt.Errorf("line %d: external symbol %s used in an expression", a.line, expr.undefined)
+	}
+	a.externals = append(a.externals, ExternalRef{
+		Symbol:  operand,
+		Address: address,
+		Size:    size,
+		High:    high,
+	})
+	return nil
+}
+
+// invertedBranches pairs each branch with the one taken on the opposite
+// condition
+var invertedBranches = map[string]string{
+	"BCC": "BCS", "BCS": "BCC",
+	"BEQ": "BNE", "BNE": "BEQ",
+	"BMI": "BPL", "BPL": "BMI",
+	"BVC": "BVS", "BVS": "BVC",
+}
+
+// branchRangeError describes a branch whose target is out of reach,
+// suggesting the inverted branch around a JMP that replaces it
+func (a *Assembler) branchRangeError(line *Line, offset int16) error {
+	target := fmt.Sprintf("$%04X", line.Value)
+	if line.SymbolName != "" {
+		target = fmt.Sprintf("%s ($%04X)", line.SymbolName, line.Value)
+	}
+	err := fmt.Sprintf("line %d: %s at $%04X to %s is out of range (%+d bytes, limit -128..+127)",
+		line.LineNum, line.Instruction, a.pc, target, offset)
+	if inverted, ok := invertedBranches[line.Instruction]; ok {
+		jump := line.SymbolName
+		if jump == "" {
+			jump = fmt.Sprintf("$%04X", line.Value)
+		}
+		err += fmt.Sprintf("; branch around a jump instead: %s skip / JMP %s / skip:", inverted, jump)
+	}
+	return fmt.Errorf("%s", err)
+}
+
+// GetOutput returns the assembled image: every output block laid out by
+// origin with gaps zero-filled. Segments lists the blocks separately.
 func (a *Assembler) GetOutput() []byte {
-	return a.output
+	blocks := a.blocks()
+	if len(blocks) == 1 {
+		return blocks[0].Data
+	}
+	return layoutSegments(blocks)
+}
+
+// Result returns the output and diagnostics of the last assembly
+func (a *Assembler) Result() AsmResult {
+	return AsmResult{
+		Output:    a.GetOutput(),
+		Warnings:  a.warnings,
+		Externals: a.externals,
+		Branches:  a.branches,
+	}
+}
+
+// warnf records a non-fatal diagnostic for the current line. Warnings are
+// only collected on pass 2 so each is reported once.
+func (a *Assembler) warnf(format string, args ...interface{}) {
+	if a.currentPass != 2 {
+		return
+	}
+	a.warnings = append(a.warnings, fmt.Sprintf("line %d: ", a.line)+fmt.Sprintf(format, args...))
 }
@@ -0,0 +1,81 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Checksum types accepted by .checksum
+const (
+	ChecksumSum8  = "sum8"  // 8-bit sum of the bytes, one byte
+	ChecksumCRC16 = "crc16" // CRC-16/CCITT-FALSE, two bytes little-endian
+)
+
+// handleChecksum processes .checksum start, end, type. It emits the checksum
+// of the already assembled bytes from start to end (inclusive) at the current
+// PC, so the range must lie before the directive in the active segment.
+func handleChecksum(a *Assembler, operand string) error {
+	parts := strings.Split(operand, ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("line %d: .checksum requires start, end, type", a.line)
+	}
+	kind := strings.ToLower(strings.TrimSpace(parts[2]))
+	var size uint16
+	switch kind {
+	case ChecksumSum8:
+		size = 1
+	case ChecksumCRC16:
+		size = 2
+	default:
+		return fmt.Errorf("line %d: unknown checksum type %q", a.line, parts[2])
+	}
+
+	if a.currentPass == 2 {
+		start, err := a.parseValue(parts[0])
+		if err != nil {
+			return err
+		}
+		end, err := a.parseValue(parts[1])
+		if err != nil {
+			return err
+		}
+		if start < a.origin || end < start || end >= a.pc {
+			return fmt.Errorf("line %d: checksum range $%04X-$%04X is not assembled before $%04X", a.line, start, end, a.pc)
+		}
+		data := a.output[start-a.origin : end-a.origin+1]
+		switch kind {
+		case ChecksumSum8:
+			a.output = append(a.output, sum8(data))
+		case ChecksumCRC16:
+			crc := crc16(data)
+			a.output = append(a.output, uint8(crc&0xFF), uint8(crc>>8))
+		}
+	}
+	a.pc += size
+	return nil
+}
+
+// sum8 adds the bytes modulo 256
+func sum8(data []byte) uint8 {
+	var sum uint8
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+// crc16 computes CRC-16/CCITT-FALSE (polynomial $1021, initial value $FFFF)
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,50 @@
+package assembler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lineError is an assembly error tied to the source line that caused it
+type lineError struct {
+	line    int
+	message string
+}
+
+// addError records err against lineNum and lets assembly continue with the
+// next line. Messages not already naming a line are prefixed with it, and an
+// error seen again on a later pass is kept once.
+func (a *Assembler) addError(lineNum int, err error) {
+	message := err.Error()
+	if !strings.HasPrefix(message, "line ") {
+		message = fmt.Sprintf("line %d: %s", lineNum, message)
+	}
+	for _, e := range a.errors {
+		if e.message == message {
+			return
+		}
+	}
+	a.errors = append(a.errors, lineError{line: lineNum, message: message})
+}
+
+// Errors returns every error of the last assembly, ordered by line
+func (a *Assembler) Errors() []string {
+	sorted := append([]lineError(nil), a.errors...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].line < sorted[j].line
+	})
+	messages := make([]string, len(sorted))
+	for i, e := range sorted {
+		messages[i] = e.message
+	}
+	return messages
+}
+
+// errorResult summarizes the collected errors as the error Assemble returns
+func (a *Assembler) errorResult() error {
+	if len(a.errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(a.Errors(), "\n"))
+}
@@ -0,0 +1,219 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// expression is the result of evaluating an operand expression
+type expression struct {
+	value     uint16
+	symbols   []string // Symbols referenced, in source order
+	undefined string   // First referenced symbol without a value yet
+}
+
+// errDivisionByZero is reported for a constant divisor of zero
+var errDivisionByZero = errors.New("division by zero")
+
+// evaluate computes an operand expression built from numbers ($hex, %binary
+// or decimal), character literals ('A', or ^'A' for its screen code),
+// symbols, + - * /, unary minus and parentheses. A leading < or > selects
+// the low or high byte of everything that follows, so #>label+1 is the high
+// byte of label+1. Undefined symbols evaluate to 0 and are reported
+// in undefined, leaving the caller to decide whether that is an error.
+func (a *Assembler) evaluate(s string) (expression, error) {
+	e := &exprParser{assembler: a, input: strings.TrimSpace(s)}
+	value, err := e.parseExpr()
+	if err != nil {
+		return expression{}, err
+	}
+	e.skipSpace()
+	if e.pos < len(e.input) {
+		return expression{}, fmt.Errorf("unexpected %q in expression %s", e.input[e.pos:], e.input)
+	}
+	return expression{value: uint16(value), symbols: e.symbols, undefined: e.undefined}, nil
+}
+
+// exprParser is a recursive descent parser over a single expression
+type exprParser struct {
+	assembler *Assembler
+	input     string
+	pos       int
+	symbols   []string
+	undefined string
+}
+
+func (e *exprParser) skipSpace() {
+	for e.pos < len(e.input) && (e.input[e.pos] == ' ' || e.input[e.pos] == '\t') {
+		e.pos++
+	}
+}
+
+// peek returns the next non-space character, or 0 at the end of input
+func (e *exprParser) peek() byte {
+	e.skipSpace()
+	if e.pos >= len(e.input) {
+		return 0
+	}
+	return e.input[e.pos]
+}
+
+// parseExpr handles the byte selectors, which bind loosest
+func (e *exprParser) parseExpr() (int, error) {
+	switch e.peek() {
+	case '<':
+		e.pos++
+		value, err := e.parseSum()
+		return value & 0xFF, err
+	case '>':
+		e.pos++
+		value, err := e.parseSum()
+		return (value >> 8) & 0xFF, err
+	}
+	return e.parseSum()
+}
+
+func (e *exprParser) parseSum() (int, error) {
+	value, err := e.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		if op != '+' && op != '-' {
+			return value, nil
+		}
+		e.pos++
+		rhs, err := e.parseTerm()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			value += rhs
+		} else {
+			value -= rhs
+		}
+	}
+}
+
+func (e *exprParser) parseTerm() (int, error) {
+	value, err := e.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		if op != '*' && op != '/' {
+			return value, nil
+		}
+		e.pos++
+		rhs, err := e.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			value *= rhs
+		} else if rhs != 0 {
+			value /= rhs
+		} else if e.undefined == "" {
+			// A zero from a forward reference may resolve on a later pass
+			return 0, errDivisionByZero
+		} else {
+			value = 0
+		}
+	}
+}
+
+func (e *exprParser) parseUnary() (int, error) {
+	if e.peek() == '-' {
+		e.pos++
+		value, err := e.parseUnary()
+		return -value, err
+	}
+	return e.parsePrimary()
+}
+
+func (e *exprParser) parsePrimary() (int, error) {
+	ch := e.peek()
+	switch {
+	case ch == '(':
+		e.pos++
+		value, err := e.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ')' {
+			return 0, fmt.Errorf("missing ) in expression %s", e.input)
+		}
+		e.pos++
+		return value, nil
+	case ch == '$':
+		e.pos++
+		return e.parseNumber(16, isHexDigit)
+	case ch == '%':
+		e.pos++
+		return e.parseNumber(2, func(c byte) bool { return c == '0' || c == '1' })
+	case isDigit(ch):
+		return e.parseNumber(10, isDigit)
+	case ch == '\'':
+		return e.parseChar(ascii)
+	case ch == '^':
+		e.pos++
+		if e.peek() != '\'' {
+			return 0, fmt.Errorf("^ must be followed by a character literal in %s", e.input)
+		}
+		return e.parseChar(screenCode)
+	case isLetter(ch):
+		start := e.pos
+		for e.pos < len(e.input) && (isLetter(e.input[e.pos]) || isDigit(e.input[e.pos])) {
+			e.pos++
+		}
+		return e.symbol(e.input[start:e.pos]), nil
+	case ch == 0:
+		return 0, fmt.Errorf("missing operand in expression %s", e.input)
+	}
+	return 0, fmt.Errorf("unexpected %q in expression %s", ch, e.input)
+}
+
+func (e *exprParser) parseNumber(base int, digit func(byte) bool) (int, error) {
+	start := e.pos
+	for e.pos < len(e.input) && digit(e.input[e.pos]) {
+		e.pos++
+	}
+	value, err := strconv.ParseUint(e.input[start:e.pos], base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number in expression %s", e.input)
+	}
+	return int(value), nil
+}
+
+// parseChar reads a single-quoted character literal, converted through
+// charset
+func (e *exprParser) parseChar(charset Charset) (int, error) {
+	start := e.pos
+	e.pos++
+	if e.pos >= len(e.input) || e.input[e.pos] == '\'' {
+		return 0, fmt.Errorf("empty character literal in %s", e.input)
+	}
+	ch, next := unescape(e.input, e.pos)
+	if next >= len(e.input) || e.input[next] != '\'' {
+		return 0, fmt.Errorf("unterminated character literal %s", e.input[start:])
+	}
+	e.pos = next + 1
+	return int(charset(ch)), nil
+}
+
+// symbol returns the value of name, noting it as referenced
+func (e *exprParser) symbol(name string) int {
+	e.symbols = append(e.symbols, name)
+	e.assembler.reference(name)
+	if symbol, exists := e.assembler.symbols[name]; exists {
+		return int(symbol.Value)
+	}
+	if e.undefined == "" {
+		e.undefined = name
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package assembler
+
+import (
+	"fmt"
+	"sort"
+)
+
+// OutputFormatter turns an assembly result into the bytes of an output file
+type OutputFormatter interface {
+	Format(result AsmResult) ([]byte, error)
+}
+
+// FormatterFunc adapts a function to OutputFormatter
+type FormatterFunc func(result AsmResult) ([]byte, error)
+
+func (f FormatterFunc) Format(result AsmResult) ([]byte, error) {
+	return f(result)
+}
+
+// formatters holds the output formats selectable by name
+var formatters = map[string]OutputFormatter{
+	"bin": FormatterFunc(formatBinary),
+}
+
+// RegisterFormat makes formatter selectable as name, replacing any existing
+// format of that name
+func RegisterFormat(name string, formatter OutputFormatter) {
+	formatters[name] = formatter
+}
+
+// FormatNames lists the registered formats, sorted
+func FormatNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatOutput renders result with the format registered as name
+func FormatOutput(name string, result AsmResult) ([]byte, error) {
+	formatter, exists := formatters[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown output format %q", name)
+	}
+	return formatter.Format(result)
+}
+
+// formatBinary writes the raw assembled image
+func formatBinary(result AsmResult) ([]byte, error) {
+	return result.Output, nil
+}
@@ -0,0 +1,82 @@
+package assembler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth bounds how deeply .include files may nest
+const maxIncludeDepth = 16
+
+// includeFrame is the suspended state of a file containing an .include, or
+// of the source around a .repeat block being expanded
+type includeFrame struct {
+	lexer  *Lexer
+	dir    string
+	path   string
+	repeat *repeatBlock
+}
+
+// AssembleFile assembles the source in path, resolving its .include
+// directives relative to the file's directory.
+func (a *Assembler) AssembleFile(path string) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading source: %v", err)
+	}
+	a.BaseDir = filepath.Dir(path)
+	a.path = filepath.Clean(path)
+	return a.Assemble(string(source))
+}
+
+// include switches the parser to the file named by an .include line. The
+// including file resumes once the included one is exhausted.
+func (p *Parser) include(line *Line) error {
+	name := strings.Trim(strings.TrimSpace(line.Operand), "\"")
+	if name == "" {
+		return fmt.Errorf("line %d: .include requires a file name", line.LineNum)
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.dir, path)
+	}
+
+	if len(p.includes) >= maxIncludeDepth {
+		return fmt.Errorf("line %d: .include %s: nested more than %d files deep", line.LineNum, name, maxIncludeDepth)
+	}
+	if path == p.path {
+		return fmt.Errorf("line %d: .include %s: file includes itself", line.LineNum, name)
+	}
+	for _, frame := range p.includes {
+		if frame.path == path {
+			return fmt.Errorf("line %d: .include %s: recursive include", line.LineNum, name)
+		}
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("line %d: .include %s: %v", line.LineNum, name, err)
+	}
+	p.includes = append(p.includes, includeFrame{lexer: p.lexer, dir: p.dir, path: p.path})
+	p.lexer = NewLexer(string(source))
+	p.dir = filepath.Dir(path)
+	p.path = path
+	return nil
+}
+
+// endInclude resumes the including file, or starts the next copy of a
+// repeat block, reporting false at the end of the top-level source.
+func (p *Parser) endInclude() bool {
+	if len(p.includes) == 0 {
+		return false
+	}
+	frame := p.includes[len(p.includes)-1]
+	if frame.repeat != nil && p.nextIteration(frame.repeat) {
+		return true
+	}
+	p.includes = p.includes[:len(p.includes)-1]
+	p.lexer, p.dir, p.path = frame.lexer, frame.dir, frame.path
+	return true
+}
@@ -17,6 +17,8 @@ const (
 	IndirectX
 	IndirectY
 	Relative
+	ZeroPageIndirect        // 65C02 (zp)
+	AbsoluteIndexedIndirect // 65C02 JMP (abs,X)
 )
 
 // Instruction represents a 6502 assembly instruction
@@ -31,6 +33,21 @@ type Instruction struct {
 type InstructionEntry struct {
 	BaseOpcode byte
 	Modes      map[AddressMode]Instruction
+	CMOSModes  map[AddressMode]Instruction // Modes added by the 65C02; require Assembler.CMOS
+	CMOS       bool                        // 65C02 only; requires Assembler.CMOS
+}
+
+// mode looks up an addressing mode of the instruction, including the modes
+// the 65C02 added when cmos is set
+func (e InstructionEntry) mode(mode AddressMode, cmos bool) (Instruction, bool) {
+	if inst, exists := e.Modes[mode]; exists {
+		return inst, true
+	}
+	if cmos {
+		inst, exists := e.CMOSModes[mode]
+		return inst, exists
+	}
+	return Instruction{}, false
 }
 
 // Create instruction set lookup table
@@ -47,6 +64,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0x61, 2, 6, IndirectX},
 			IndirectY: {0x71, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0x72, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"AND": {
 		BaseOpcode: 0x29,
@@ -60,6 +80,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0x21, 2, 6, IndirectX},
 			IndirectY: {0x31, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0x32, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"ASL": {
 		BaseOpcode: 0x0A,
@@ -77,6 +100,11 @@ var instructionSet = map[string]InstructionEntry{
 			ZeroPage: {0x24, 2, 3, ZeroPage},
 			Absolute: {0x2C, 3, 4, Absolute},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			Immediate: {0x89, 2, 2, Immediate},
+			ZeroPageX: {0x34, 2, 4, ZeroPageX},
+			AbsoluteX: {0x3C, 3, 4, AbsoluteX},
+		},
 	},
 	"BPL": {BaseOpcode: 0x10, Modes: map[AddressMode]Instruction{Relative: {0x10, 2, 2, Relative}}},
 	"BMI": {BaseOpcode: 0x30, Modes: map[AddressMode]Instruction{Relative: {0x30, 2, 2, Relative}}},
@@ -99,6 +127,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0xC1, 2, 6, IndirectX},
 			IndirectY: {0xD1, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0xD2, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"CPX": {
 		BaseOpcode: 0xE0,
@@ -124,6 +155,9 @@ var instructionSet = map[string]InstructionEntry{
 			Absolute:  {0xCE, 3, 6, Absolute},
 			AbsoluteX: {0xDE, 3, 7, AbsoluteX},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			Accumulator: {0x3A, 1, 2, Accumulator},
+		},
 	},
 	"EOR": {
 		BaseOpcode: 0x49,
@@ -137,6 +171,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0x41, 2, 6, IndirectX},
 			IndirectY: {0x51, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0x52, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"CLC": {BaseOpcode: 0x18, Modes: map[AddressMode]Instruction{Implicit: {0x18, 1, 2, Implicit}}},
 	"SEC": {BaseOpcode: 0x38, Modes: map[AddressMode]Instruction{Implicit: {0x38, 1, 2, Implicit}}},
@@ -153,6 +190,9 @@ var instructionSet = map[string]InstructionEntry{
 			Absolute:  {0xEE, 3, 6, Absolute},
 			AbsoluteX: {0xFE, 3, 7, AbsoluteX},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			Accumulator: {0x1A, 1, 2, Accumulator},
+		},
 	},
 	"JMP": {
 		BaseOpcode: 0x4C,
@@ -160,6 +200,9 @@ var instructionSet = map[string]InstructionEntry{
 			Absolute: {0x4C, 3, 3, Absolute},
 			Indirect: {0x6C, 3, 5, Indirect},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			AbsoluteIndexedIndirect: {0x7C, 3, 6, AbsoluteIndexedIndirect},
+		},
 	},
 	"JSR": {BaseOpcode: 0x20, Modes: map[AddressMode]Instruction{Absolute: {0x20, 3, 6, Absolute}}},
 	"LDA": {
@@ -174,6 +217,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0xA1, 2, 6, IndirectX},
 			IndirectY: {0xB1, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0xB2, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"LDX": {
 		BaseOpcode: 0xA2,
@@ -218,6 +264,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0x01, 2, 6, IndirectX},
 			IndirectY: {0x11, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0x12, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"PHA": {BaseOpcode: 0x48, Modes: map[AddressMode]Instruction{Implicit: {0x48, 1, 3, Implicit}}},
 	"PHP": {BaseOpcode: 0x08, Modes: map[AddressMode]Instruction{Implicit: {0x08, 1, 3, Implicit}}},
@@ -257,6 +306,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0xE1, 2, 6, IndirectX},
 			IndirectY: {0xF1, 2, 5, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0xF2, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"STA": {
 		BaseOpcode: 0x85,
@@ -269,6 +321,9 @@ var instructionSet = map[string]InstructionEntry{
 			IndirectX: {0x81, 2, 6, IndirectX},
 			IndirectY: {0x91, 2, 6, IndirectY},
 		},
+		CMOSModes: map[AddressMode]Instruction{
+			ZeroPageIndirect: {0x92, 2, 5, ZeroPageIndirect},
+		},
 	},
 	"STX": {
 		BaseOpcode: 0x86,
@@ -296,4 +351,41 @@ var instructionSet = map[string]InstructionEntry{
 	"DEY": {BaseOpcode: 0x88, Modes: map[AddressMode]Instruction{Implicit: {0x88, 1, 2, Implicit}}},
 	"INX": {BaseOpcode: 0xE8, Modes: map[AddressMode]Instruction{Implicit: {0xE8, 1, 2, Implicit}}},
 	"INY": {BaseOpcode: 0xC8, Modes: map[AddressMode]Instruction{Implicit: {0xC8, 1, 2, Implicit}}},
+
+	// 65C02 additions
+	"BRA": {BaseOpcode: 0x80, Modes: map[AddressMode]Instruction{Relative: {0x80, 2, 3, Relative}}, CMOS: true},
+	"PHX": {BaseOpcode: 0xDA, Modes: map[AddressMode]Instruction{Implicit: {0xDA, 1, 3, Implicit}}, CMOS: true},
+	"PHY": {BaseOpcode: 0x5A, Modes: map[AddressMode]Instruction{Implicit: {0x5A, 1, 3, Implicit}}, CMOS: true},
+	"PLX": {BaseOpcode: 0xFA, Modes: map[AddressMode]Instruction{Implicit: {0xFA, 1, 4, Implicit}}, CMOS: true},
+	"PLY": {BaseOpcode: 0x7A, Modes: map[AddressMode]Instruction{Implicit: {0x7A, 1, 4, Implicit}}, CMOS: true},
+	"STZ": {
+		BaseOpcode: 0x64,
+		Modes: map[AddressMode]Instruction{
+			ZeroPage:  {0x64, 2, 3, ZeroPage},
+			ZeroPageX: {0x74, 2, 4, ZeroPageX},
+			Absolute:  {0x9C, 3, 4, Absolute},
+			AbsoluteX: {0x9E, 3, 5, AbsoluteX},
+		},
+		CMOS: true,
+	},
+	"TSB": {
+		BaseOpcode: 0x04,
+		Modes: map[AddressMode]Instruction{
+			ZeroPage: {0x04, 2, 5, ZeroPage},
+			Absolute: {0x0C, 3, 6, Absolute},
+		},
+		CMOS: true,
+	},
+	"TRB": {
+		BaseOpcode: 0x14,
+		Modes: map[AddressMode]Instruction{
+			ZeroPage: {0x14, 2, 5, ZeroPage},
+			Absolute: {0x1C, 3, 6, Absolute},
+		},
+		CMOS: true,
+	},
+
+	// 65C02 power management
+	"WAI": {BaseOpcode: 0xCB, Modes: map[AddressMode]Instruction{Implicit: {0xCB, 1, 3, Implicit}}, CMOS: true},
+	"STP": {BaseOpcode: 0xDB, Modes: map[AddressMode]Instruction{Implicit: {0xDB, 1, 3, Implicit}}, CMOS: true},
 }
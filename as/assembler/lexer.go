@@ -55,6 +55,8 @@ func (l *Lexer) NextToken() Token {
 		return l.readNumber()
 	case char == ';':
 		return l.readComment()
+	case char == '"' || char == '\'':
+		return l.readQuoted(char)
 	case char == ':':
 		l.position++
 		if l.lastToken.Type == INSTRUCTION {
@@ -130,6 +132,46 @@ func (l *Lexer) readNumber() Token {
 	}
 }
 
+// readQuoted reads a string or character literal as a single operand token,
+// quotes and escapes included, so spaces and commas inside it survive. An
+// unterminated literal ends at the end of the line.
+func (l *Lexer) readQuoted(quote byte) Token {
+	position := l.position
+	l.position++
+	for l.position < len(l.input) && l.input[l.position] != '\n' {
+		ch := l.input[l.position]
+		l.position++
+		if ch == '\\' && l.position < len(l.input) && l.input[l.position] != '\n' {
+			l.position++
+		} else if ch == quote {
+			break
+		}
+	}
+	return Token{
+		Type:    OPERAND,
+		Value:   l.input[position:l.position],
+		LineNum: l.lineNum,
+	}
+}
+
+// readLine consumes the rest of the current line and returns it unlexed,
+// reporting false at the end of the input
+func (l *Lexer) readLine() (string, bool) {
+	if l.position >= len(l.input) {
+		return "", false
+	}
+	start := l.position
+	for l.position < len(l.input) && l.input[l.position] != '\n' {
+		l.position++
+	}
+	text := l.input[start:l.position]
+	if l.position < len(l.input) {
+		l.position++
+		l.lineNum++
+	}
+	return strings.TrimRight(text, "\r"), true
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.position < len(l.input) && (l.input[l.position] == ' ' || l.input[l.position] == '\t' || l.input[l.position] == '\r') {
 		l.position++
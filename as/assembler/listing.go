@@ -0,0 +1,28 @@
+package assembler
+
+// ListEntry is one source line of a listing with the bytes it assembled to
+type ListEntry struct {
+	Line   int
+	Addr   uint16 // Address of the first byte, or the PC after the line if it emitted none
+	Bytes  []byte
+	Source string
+}
+
+// listLine records the bytes generateCode emitted for line, which started at
+// pc with emitted bytes already in the output of segment. Lines that switch
+// segments or move the PC with .org list the new PC and no bytes, since any
+// padding they emit is not their own.
+func (a *Assembler) listLine(line *Line, pc uint16, segment string, emitted int) {
+	entry := ListEntry{Line: line.LineNum, Addr: a.pc, Source: line.Source}
+	if a.segment == segment && line.Directive != ".org" && len(a.output) > emitted {
+		entry.Addr = pc
+		entry.Bytes = append([]byte(nil), a.output[emitted:]...)
+	}
+	a.listing = append(a.listing, entry)
+}
+
+// Listing returns every source line of the last assembly, blank and
+// comment-only lines included, with its address and emitted bytes
+func (a *Assembler) Listing() []ListEntry {
+	return a.listing
+}
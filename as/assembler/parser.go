@@ -2,7 +2,6 @@ package assembler
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -12,6 +11,10 @@ type Parser struct {
 	assembler *Assembler
 	tokens    []Token
 	position  int
+
+	dir      string // Directory .include paths resolve against
+	path     string // File being read, if known
+	includes []includeFrame
 }
 
 // Line represents a parsed assembly line
@@ -24,6 +27,8 @@ type Line struct {
 	Value       uint16
 	IsRelative  bool
 	SymbolName  string
+	LineNum     int
+	Source      string // Text of the line as written
 }
 
 func NewParser(lexer *Lexer, assembler *Assembler) *Parser {
@@ -32,6 +37,8 @@ func NewParser(lexer *Lexer, assembler *Assembler) *Parser {
 		assembler: assembler,
 		tokens:    make([]Token, 0),
 		position:  0,
+		dir:       assembler.BaseDir,
+		path:      assembler.path,
 	}
 }
 
@@ -57,13 +64,13 @@ func (p *Parser) detectAddressMode(line *Line) error {
 	if operand == "" {
 		// Check if this instruction can use accumulator mode with no operand
 		switch line.Instruction {
-		case "LSR", "ASL", "ROL", "ROR":
-			if _, supported := inst.Modes[Accumulator]; supported {
+		case "LSR", "ASL", "ROL", "ROR", "INC", "DEC":
+			if _, supported := inst.mode(Accumulator, p.assembler.CMOS); supported {
 				line.AddressMode = Accumulator
 				return nil
 			}
 		}
-		if _, supported := inst.Modes[Implicit]; supported {
+		if _, supported := inst.mode(Implicit, p.assembler.CMOS); supported {
 			line.AddressMode = Implicit
 			return nil
 		}
@@ -71,7 +78,7 @@ func (p *Parser) detectAddressMode(line *Line) error {
 	}
 
 	if operand == "A" || operand == "a" {
-		if _, supported := inst.Modes[Accumulator]; supported {
+		if _, supported := inst.mode(Accumulator, p.assembler.CMOS); supported {
 			line.AddressMode = Accumulator
 			return nil
 		}
@@ -86,10 +93,10 @@ func (p *Parser) detectAddressMode(line *Line) error {
 
 	// Immediate addressing (#$xx or #xx)
 	if strings.HasPrefix(operand, "#") {
-		if _, supported := inst.Modes[Immediate]; supported {
+		if _, supported := inst.mode(Immediate, p.assembler.CMOS); supported {
 			line.AddressMode = Immediate
-			line.Value = p.parseValue(operand[1:])
-			return nil
+			_, err := p.operandValue(line, operand[1:])
+			return err
 		}
 		return fmt.Errorf("instruction %s does not support immediate mode", line.Instruction)
 	}
@@ -97,38 +104,36 @@ func (p *Parser) detectAddressMode(line *Line) error {
 	// Indirect addressing
 	if strings.HasPrefix(operand, "(") {
 		if strings.HasSuffix(operand, ",X)") {
-			if _, supported := inst.Modes[IndirectX]; supported {
+			if _, supported := inst.mode(IndirectX, p.assembler.CMOS); supported {
 				line.AddressMode = IndirectX
-				base := operand[1 : len(operand)-3]
-				if !isNumeric(base) {
-					line.SymbolName = base
-				}
-				line.Value = p.parseValue(base)
-				return nil
+				_, err := p.operandValue(line, operand[1:len(operand)-3])
+				return err
+			}
+			if _, supported := inst.mode(AbsoluteIndexedIndirect, p.assembler.CMOS); supported {
+				line.AddressMode = AbsoluteIndexedIndirect
+				_, err := p.operandValue(line, operand[1:len(operand)-3])
+				return err
 			}
 			return fmt.Errorf("instruction %s does not support indirect X mode", line.Instruction)
 		}
 		if strings.HasSuffix(operand, "),Y") {
-			if _, supported := inst.Modes[IndirectY]; supported {
+			if _, supported := inst.mode(IndirectY, p.assembler.CMOS); supported {
 				line.AddressMode = IndirectY
-				base := operand[1 : len(operand)-3]
-				if !isNumeric(base) {
-					line.SymbolName = base
-				}
-				line.Value = p.parseValue(base)
-				return nil
+				_, err := p.operandValue(line, operand[1:len(operand)-3])
+				return err
 			}
 			return fmt.Errorf("instruction %s does not support indirect Y mode", line.Instruction)
 		}
 		if strings.HasSuffix(operand, ")") {
-			if _, supported := inst.Modes[Indirect]; supported {
+			if _, supported := inst.mode(Indirect, p.assembler.CMOS); supported {
 				line.AddressMode = Indirect
-				base := operand[1 : len(operand)-1]
-				if !isNumeric(base) {
-					line.SymbolName = base
-				}
-				line.Value = p.parseValue(base)
-				return nil
+				_, err := p.operandValue(line, operand[1:len(operand)-1])
+				return err
+			}
+			if _, supported := inst.mode(ZeroPageIndirect, p.assembler.CMOS); supported {
+				line.AddressMode = ZeroPageIndirect
+				_, err := p.operandValue(line, operand[1:len(operand)-1])
+				return err
 			}
 			return fmt.Errorf("instruction %s does not support indirect mode", line.Instruction)
 		}
@@ -136,27 +141,21 @@ func (p *Parser) detectAddressMode(line *Line) error {
 
 	// X/Y indexing
 	if strings.HasSuffix(operand, ",X") {
-		base := operand[:len(operand)-2]
-		value := p.parseValue(base)
+		expr, err := p.operandValue(line, operand[:len(operand)-2])
+		if err != nil {
+			return err
+		}
 
 		// Try zero page X if value fits and mode is supported
-		if value < 0x100 {
-			if _, supported := inst.Modes[ZeroPageX]; supported {
+		if fitsZeroPage(expr) {
+			if _, supported := inst.mode(ZeroPageX, p.assembler.CMOS); supported {
 				line.AddressMode = ZeroPageX
-				if !isNumeric(base) {
-					line.SymbolName = base
-				}
-				line.Value = value
 				return nil
 			}
 		}
 
-		if _, supported := inst.Modes[AbsoluteX]; supported {
+		if _, supported := inst.mode(AbsoluteX, p.assembler.CMOS); supported {
 			line.AddressMode = AbsoluteX
-			if !isNumeric(base) {
-				line.SymbolName = base
-			}
-			line.Value = value
 			return nil
 		}
 
@@ -164,27 +163,21 @@ func (p *Parser) detectAddressMode(line *Line) error {
 	}
 
 	if strings.HasSuffix(operand, ",Y") {
-		base := operand[:len(operand)-2]
-		value := p.parseValue(base)
+		expr, err := p.operandValue(line, operand[:len(operand)-2])
+		if err != nil {
+			return err
+		}
 
 		// Try zero page Y if value fits and mode is supported
-		if value < 0x100 {
-			if _, supported := inst.Modes[ZeroPageY]; supported {
+		if fitsZeroPage(expr) {
+			if _, supported := inst.mode(ZeroPageY, p.assembler.CMOS); supported {
 				line.AddressMode = ZeroPageY
-				if !isNumeric(base) {
-					line.SymbolName = base
-				}
-				line.Value = value
 				return nil
 			}
 		}
 
-		if _, supported := inst.Modes[AbsoluteY]; supported {
+		if _, supported := inst.mode(AbsoluteY, p.assembler.CMOS); supported {
 			line.AddressMode = AbsoluteY
-			if !isNumeric(base) {
-				line.SymbolName = base
-			}
-			line.Value = value
 			return nil
 		}
 
@@ -192,35 +185,26 @@ func (p *Parser) detectAddressMode(line *Line) error {
 	}
 
 	// Non-indexed addressing
-	value := p.parseValue(operand)
+	expr, err := p.operandValue(line, operand)
+	if err != nil {
+		return err
+	}
 
 	// Try zero page if value fits and mode is supported
-	if value < 0x100 {
-		if _, supported := inst.Modes[ZeroPage]; supported {
+	if fitsZeroPage(expr) {
+		if _, supported := inst.mode(ZeroPage, p.assembler.CMOS); supported {
 			line.AddressMode = ZeroPage
-			if !isNumeric(operand) {
-				line.SymbolName = operand
-			}
-			line.Value = value
 			return nil
 		}
 	}
 
-	if _, supported := inst.Modes[Absolute]; supported {
+	if _, supported := inst.mode(Absolute, p.assembler.CMOS); supported {
 		line.AddressMode = Absolute
-		if !isNumeric(operand) {
-			line.SymbolName = operand
-		}
-		line.Value = value
 		return nil
 	}
 
-	if _, supported := inst.Modes[Relative]; supported {
+	if _, supported := inst.mode(Relative, p.assembler.CMOS); supported {
 		line.AddressMode = Relative
-		if !isNumeric(operand) {
-			line.SymbolName = operand
-		}
-		line.Value = value
 		return nil
 	}
 
@@ -228,60 +212,58 @@ func (p *Parser) detectAddressMode(line *Line) error {
 		line.Instruction, line.Operand)
 }
 
-// isNumeric checks if the string represents a number (hex, binary, or decimal)
-func isNumeric(s string) bool {
-	s = strings.TrimSpace(s)
-	if strings.HasPrefix(s, "$") || strings.HasPrefix(s, "%") {
-		return true
+// operandValue evaluates an address operand into line.Value. Operands
+// referencing symbols are kept in line.SymbolName so pass 2 can evaluate
+// them again once every symbol is known.
+func (p *Parser) operandValue(line *Line, base string) (expression, error) {
+	expr, err := p.assembler.evaluate(base)
+	if err != nil {
+		return expr, fmt.Errorf("line %d: %v", line.LineNum, err)
 	}
-	_, err := strconv.ParseUint(s, 10, 16)
-	return err == nil
-}
-
-// parseValue converts a string value to uint16
-func (p *Parser) parseValue(s string) uint16 {
-	s = strings.TrimSpace(s)
-
-	// Check for hex value ($)
-	if strings.HasPrefix(s, "$") {
-		val, err := strconv.ParseUint(s[1:], 16, 16)
-		if err == nil {
-			return uint16(val)
-		}
+	if len(expr.symbols) > 0 {
+		line.SymbolName = strings.TrimSpace(base)
 	}
+	line.Value = expr.value
+	return expr, nil
+}
 
-	// Check for binary value (%)
-	if strings.HasPrefix(s, "%") {
-		val, err := strconv.ParseUint(s[1:], 2, 16)
-		if err == nil {
-			return uint16(val)
-		}
-	}
+// fitsZeroPage reports whether an evaluated operand can use a zero-page
+// mode. Expressions using symbols not yet defined are assumed to be 16-bit so
+// pass 1 never under-sizes an instruction; a later sizing pass shrinks it
+// once the value is known.
+func fitsZeroPage(expr expression) bool {
+	return expr.value < 0x100 && expr.undefined == ""
+}
 
-	// Check if it's a symbol
-	if p.assembler.symbols != nil {
-		if symbol, exists := p.assembler.symbols[s]; exists {
-			return symbol.Value
-		}
-	}
+// parseValue evaluates an expression, reporting errors against the line
+// being parsed
+func (p *Parser) parseValue(s string) (uint16, error) {
+	return p.assembler.parseValue(s)
+}
 
-	// Try decimal
-	val, err := strconv.ParseUint(s, 10, 16)
-	if err == nil {
-		return uint16(val)
+// parseValue evaluates an expression for the current line. Undefined
+// symbols evaluate to 0.
+func (a *Assembler) parseValue(s string) (uint16, error) {
+	expr, err := a.evaluate(s)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: %v", a.line, err)
 	}
-
-	return 0
+	return expr.value, nil
 }
 
 func (p *Parser) ParseLine() (*Line, error) {
 	p.tokens = make([]Token, 0)
+	start, lineNum := p.lexer.position, p.lexer.lineNum
 
 	// Collect all tokens until EOL
 	for {
 		token := p.lexer.NextToken()
 		if token.Type == EOF {
 			if len(p.tokens) == 0 {
+				if p.endInclude() {
+					start, lineNum = p.lexer.position, p.lexer.lineNum
+					continue
+				}
 				return nil, nil
 			}
 			break
@@ -294,10 +276,13 @@ func (p *Parser) ParseLine() (*Line, error) {
 		}
 	}
 
-	line := &Line{}
+	line := &Line{LineNum: lineNum}
+	line.Source = strings.TrimRight(p.lexer.input[start:p.lexer.position], "\r\n")
 	if len(p.tokens) == 0 {
 		return line, nil
 	}
+	line.LineNum = p.tokens[0].LineNum
+	p.assembler.line = line.LineNum
 	p.position = 0
 
 	if p.position < len(p.tokens) {
@@ -306,7 +291,27 @@ func (p *Parser) ParseLine() (*Line, error) {
 			line.Label = token.Value
 			p.position++
 			if p.position < len(p.tokens) {
-				if p.tokens[p.position].Type == OPERAND {
+				next := p.tokens[p.position]
+				// NAME = value and NAME .equ value assign a constant
+				// instead of the PC
+				if next.Type == DIRECTIVE && strings.ToLower(next.Value) == ".equ" {
+					p.position++
+					line.Directive = "="
+					line.Operand = p.parseOperand()
+					return line, nil
+				}
+				if next.Type == OPERAND {
+					if next.Value == "=" {
+						p.position++
+						line.Directive = "="
+						line.Operand = p.parseOperand()
+						return line, nil
+					}
+					// Anything other than the label's colon means the
+					// identifier was a mistyped mnemonic, not a label
+					if next.Value != ":" {
+						return nil, fmt.Errorf("line %d: unknown instruction: %s", line.LineNum, token.Value)
+					}
 					p.position++
 				}
 			}
@@ -319,6 +324,22 @@ func (p *Parser) ParseLine() (*Line, error) {
 			line.Directive = strings.ToLower(token.Value)
 			p.position++
 			line.Operand = p.parseOperand()
+			// Included files are read in place of the directive, so
+			// every pass sees the same stream of lines
+			switch line.Directive {
+			case ".include":
+				if err := p.include(line); err != nil {
+					return nil, err
+				}
+				line.Directive = ""
+			case ".repeat":
+				if err := p.repeat(line); err != nil {
+					return nil, err
+				}
+				line.Directive = ""
+			case ".endrepeat":
+				return nil, fmt.Errorf("line %d: .endrepeat without .repeat", line.LineNum)
+			}
 		} else if token.Type == INSTRUCTION {
 			line.Instruction = strings.ToUpper(token.Value)
 			p.position++
@@ -326,6 +347,8 @@ func (p *Parser) ParseLine() (*Line, error) {
 			if err := p.detectAddressMode(line); err != nil {
 				return nil, err
 			}
+		} else if token.Type == LABEL {
+			return nil, fmt.Errorf("line %d: unknown instruction: %s", line.LineNum, token.Value)
 		}
 	}
 
@@ -337,44 +360,91 @@ type DirectiveHandler func(a *Assembler, operand string) error
 
 // Map of directives to their handlers
 var directiveHandlers = map[string]DirectiveHandler{
-	".org":  handleOrg,
-	".byte": handleByte,
-	".word": handleWord,
+	".org":      handleOrg,
+	".byte":     handleByte,
+	".text":     handleText,
+	".asciiz":   handleAsciiz,
+	".petscii":  handlePetscii,
+	".screen":   handleScreen,
+	".word":     handleWord,
+	".segment":  handleSegment,
+	".code":     handleCode,
+	".data":     handleData,
+	".checksum": handleChecksum,
+	".align":    handleAlign,
 }
 
-// handleOrg processes the .org directive
+// handleOrg processes the .org directive. On pass 2 it closes the output
+// block being assembled and starts a new one at the given address, so the
+// output can jump backwards as well as forwards.
 func handleOrg(a *Assembler, operand string) error {
-	value := parseNumber(operand)
-	if a.currentPass == 1 {
-		a.pc = value
-	} else {
-		// On pass 2, pad output to reach org address if needed,
-		// but if the .org directive is the first instruction.
-		if len(a.output) > 0 {
-			for count := value - a.pc; count > 0; count-- {
-				a.output = append(a.output, 0)
-			}
-		}
-		a.pc = value
+	expr, err := a.evaluate(operand)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", a.line, err)
+	}
+	// A forward reference places nothing until a later sizing pass knows it
+	if expr.undefined != "" && a.currentPass == 2 {
+		return fmt.Errorf("line %d: undefined symbol: %s", a.line, expr.undefined)
 	}
+	value := expr.value
+	if a.currentPass == 2 {
+		a.startBlock(value)
+	}
+	a.pc = value
 	return nil
 }
 
-// handleByte processes the .byte directive
-func handleByte(a *Assembler, operand string) error {
-	values := parseByteList(operand)
+// handleAlign processes the .align N[, fill] directive, padding with the fill
+// byte (default 0) until the PC is a multiple of N
+func handleAlign(a *Assembler, operand string) error {
+	parts := splitList(operand)
+	if len(parts) > 2 {
+		return fmt.Errorf(".align takes a boundary and an optional fill byte")
+	}
+	boundary, err := a.parseValue(parts[0])
+	if err != nil {
+		return err
+	}
+	if boundary == 0 {
+		return fmt.Errorf(".align boundary must be positive")
+	}
+	var fill uint16
+	if len(parts) == 2 {
+		if fill, err = a.parseValue(parts[1]); err != nil {
+			return err
+		}
+		if fill > 0xFF {
+			return fmt.Errorf(".align fill $%04X is not a byte", fill)
+		}
+	}
+
+	padding := (boundary - a.pc%boundary) % boundary
 	if a.currentPass == 2 {
-		for _, v := range values {
-			a.output = append(a.output, v)
+		for i := uint16(0); i < padding; i++ {
+			a.output = append(a.output, uint8(fill))
 		}
 	}
-	a.pc += uint16(len(values))
+	a.pc += padding
 	return nil
 }
 
+// handleByte processes the .byte directive
+func handleByte(a *Assembler, operand string) error {
+	return emitByteList(a, operand, ascii, false)
+}
+
 // handleWord processes the .word directive
 func handleWord(a *Assembler, operand string) error {
-	values := parseWordList(operand)
+	values, err := parseWordList(a, operand)
+	if err != nil {
+		return err
+	}
+	if a.WarnPageCross && len(values) > 0 {
+		end := a.pc + uint16(len(values)*2) - 1
+		if a.pc&0xFF00 != end&0xFF00 {
+			a.warnf("word table $%04X-$%04X straddles a page boundary", a.pc, end)
+		}
+	}
 	if a.currentPass == 2 {
 		for _, v := range values {
 			a.output = append(a.output, uint8(v&0xFF))
@@ -385,65 +455,67 @@ func handleWord(a *Assembler, operand string) error {
 	return nil
 }
 
-// parseByteList splits a comma-separated list of values and parses each one
-func parseByteList(operand string) []uint8 {
-	parts := strings.Split(operand, ",")
+// parseByteList splits a comma-separated list of values and parses each one.
+// String literals are converted through charset. A leading < or > selects
+// the low or high byte of a 16-bit value; any other value above $FF is
+// truncated to its low byte with a warning.
+func parseByteList(a *Assembler, operand string, charset Charset) ([]uint8, error) {
+	parts := splitList(operand)
 	values := make([]uint8, 0, len(parts))
 
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		// Handle string literals
-		if strings.HasPrefix(part, "\"") && strings.HasSuffix(part, "\"") {
-			str := part[1 : len(part)-1]
-			for _, ch := range str {
-				values = append(values, uint8(ch))
+		if strings.HasPrefix(part, "\"") {
+			chars, err := decodeString(part)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", a.line, err)
 			}
-		} else {
-			value := parseNumber(part)
-			values = append(values, uint8(value))
+			for _, ch := range chars {
+				values = append(values, charset(ch))
+			}
+			continue
+		}
+		value, err := a.dataValue(part, len(values), 1)
+		if err != nil {
+			return nil, err
 		}
+		if value > 0xFF {
+			a.warnf(".byte value $%04X truncated to $%02X", value, uint8(value))
+		}
+		values = append(values, uint8(value))
 	}
-	return values
+	return values, nil
 }
 
 // parseWordList splits a comma-separated list of values and parses each one
-func parseWordList(operand string) []uint16 {
+func parseWordList(a *Assembler, operand string) ([]uint16, error) {
 	parts := strings.Split(operand, ",")
 	values := make([]uint16, 0, len(parts))
 
 	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		value := parseNumber(part)
-		values = append(values, uint16(value))
+		value, err := a.dataValue(part, len(values)*2, 2)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
 	}
-	return values
+	return values, nil
 }
 
-// parseNumber handles different number formats (hex, binary, decimal)
-func parseNumber(s string) uint16 {
-	s = strings.TrimSpace(s)
-
-	// Handle hex ($)
-	if strings.HasPrefix(s, "$") {
-		val, err := strconv.ParseUint(s[1:], 16, 16)
-		if err == nil {
-			return uint16(val)
-		}
+// dataValue evaluates a value of a data directive stored offset bytes past
+// the PC. On pass 2 an undefined symbol is an error, or with AllowExternals
+// a size-byte fixup assembled as 0.
+func (a *Assembler) dataValue(s string, offset int, size int) (uint16, error) {
+	expr, err := a.evaluate(s)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: %v", a.line, err)
 	}
-
-	// Handle binary (%)
-	if strings.HasPrefix(s, "%") {
-		val, err := strconv.ParseUint(s[1:], 2, 16)
-		if err == nil {
-			return uint16(val)
-		}
+	if a.currentPass != 2 || expr.undefined == "" {
+		return expr.value, nil
 	}
-
-	// Handle decimal
-	val, err := strconv.ParseUint(s, 10, 16)
-	if err == nil {
-		return uint16(val)
+	if !a.AllowExternals {
+		return 0, fmt.Errorf("line %d: undefined symbol: %s", a.line, expr.undefined)
 	}
-
-	return 0
+	return 0, a.external(s, a.pc+uint16(offset), size)
 }
@@ -0,0 +1,106 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// repeatBlock is a .repeat body being expanded in place of the source
+type repeatBlock struct {
+	body      string
+	line      int // Source line of the .repeat directive
+	count     int
+	iteration int
+	counter   string // Symbol holding the iteration, if named
+}
+
+// repeat buffers the lines up to the matching .endrepeat and switches the
+// parser to the first of count copies of them. The operand is the count
+// and an optional counter symbol, which is set to the iteration (from 0)
+// before each copy is read, e.g. ".repeat 16, i".
+func (p *Parser) repeat(line *Line) error {
+	parts := splitList(line.Operand)
+	if len(parts) > 2 {
+		return fmt.Errorf("line %d: .repeat takes a count and an optional counter symbol", line.LineNum)
+	}
+	expr, err := p.assembler.evaluate(parts[0])
+	if err != nil {
+		return fmt.Errorf("line %d: %v", line.LineNum, err)
+	}
+	// A forward reference repeats nothing until a later sizing pass knows it
+	if expr.undefined != "" && p.assembler.currentPass == 2 {
+		return fmt.Errorf("line %d: undefined symbol: %s", line.LineNum, expr.undefined)
+	}
+	block := &repeatBlock{line: line.LineNum, count: int(expr.value)}
+	if len(parts) == 2 {
+		block.counter = strings.TrimSpace(parts[1])
+	}
+
+	var body []string
+	depth := 1
+	for {
+		text, ok := p.lexer.readLine()
+		if !ok {
+			return fmt.Errorf("line %d: .repeat without .endrepeat", line.LineNum)
+		}
+		switch repeatKeyword(text) {
+		case ".repeat":
+			depth++
+		case ".endrepeat":
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+		body = append(body, text)
+	}
+	block.body = strings.Join(body, "\n")
+
+	if block.count == 0 {
+		return nil
+	}
+	p.includes = append(p.includes, includeFrame{lexer: p.lexer, dir: p.dir, path: p.path, repeat: block})
+	p.startIteration(block)
+	return nil
+}
+
+// startIteration points the lexer at a fresh copy of the block's body
+func (p *Parser) startIteration(block *repeatBlock) {
+	p.lexer = NewLexer(block.body)
+	p.lexer.lineNum = block.line + 1
+	if block.counter != "" {
+		p.assembler.symbols[block.counter] = &Symbol{
+			Name:      block.counter,
+			Value:     uint16(block.iteration),
+			IsDefined: true,
+			Line:      block.line,
+		}
+	}
+}
+
+// nextIteration starts the next copy of a repeat block, reporting false once
+// every copy has been read
+func (p *Parser) nextIteration(block *repeatBlock) bool {
+	if block.iteration+1 >= block.count {
+		return false
+	}
+	block.iteration++
+	p.startIteration(block)
+	return true
+}
+
+// repeatKeyword returns the lowercased directive of a raw source line if it
+// opens or closes a repeat block, skipping any leading label
+func repeatKeyword(text string) string {
+	if i := strings.Index(text, ";"); i >= 0 {
+		text = text[:i]
+	}
+	fields := strings.Fields(text)
+	if len(fields) > 1 && strings.HasSuffix(fields[0], ":") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
@@ -0,0 +1,151 @@
+package assembler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default segment names used by the .code and .data aliases
+const (
+	CodeSegment = "CODE"
+	DataSegment = "DATA"
+)
+
+// Segment is a contiguous block of assembled output. Each .org starts a new
+// block, so one named segment may yield several.
+type Segment struct {
+	Name   string
+	Origin uint16
+	Data   []byte
+}
+
+// segmentState holds the saved position of a segment while another one is active
+type segmentState struct {
+	blocks []Segment // Output closed by a later .org, in emission order
+	origin uint16
+	pc     uint16
+	output []byte
+}
+
+// resetSegments starts a pass with only the default code segment active
+func (a *Assembler) resetSegments() {
+	a.segment = CodeSegment
+	a.segments = map[string]*segmentState{CodeSegment: {}}
+	a.segmentOrder = []string{CodeSegment}
+	a.origin = 0
+	a.pc = 0
+	a.output = make([]byte, 0)
+}
+
+// saveSegment stores the active position back into its segment
+func (a *Assembler) saveSegment() {
+	seg := a.segments[a.segment]
+	seg.origin = a.origin
+	seg.pc = a.pc
+	seg.output = a.output
+}
+
+// switchSegment makes name the active segment, creating it on first use.
+// Each segment keeps its own PC, so code and data can be interleaved in the
+// source while being laid out at different addresses.
+func (a *Assembler) switchSegment(name string) {
+	a.saveSegment()
+	seg, exists := a.segments[name]
+	if !exists {
+		seg = &segmentState{output: make([]byte, 0)}
+		a.segments[name] = seg
+		a.segmentOrder = append(a.segmentOrder, name)
+	}
+	a.segment = name
+	a.origin = seg.origin
+	a.pc = seg.pc
+	a.output = seg.output
+}
+
+// startBlock closes the active segment's current output block and starts a
+// new one at origin. An empty block is simply moved.
+func (a *Assembler) startBlock(origin uint16) {
+	if len(a.output) > 0 {
+		seg := a.segments[a.segment]
+		seg.blocks = append(seg.blocks, Segment{Name: a.segment, Origin: a.origin, Data: a.output})
+		a.output = make([]byte, 0)
+	}
+	a.origin = origin
+}
+
+// blocks returns the non-empty output blocks of the last assembly in the
+// order they were emitted
+func (a *Assembler) blocks() []Segment {
+	if a.segments == nil {
+		return nil
+	}
+	a.saveSegment()
+
+	var blocks []Segment
+	for _, name := range a.segmentOrder {
+		seg := a.segments[name]
+		blocks = append(blocks, seg.blocks...)
+		if len(seg.output) > 0 {
+			blocks = append(blocks, Segment{Name: name, Origin: seg.origin, Data: seg.output})
+		}
+	}
+	return blocks
+}
+
+// Segments returns the non-empty output blocks of the last assembly,
+// ordered by origin
+func (a *Assembler) Segments() []Segment {
+	segments := a.blocks()
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Origin < segments[j].Origin
+	})
+	return segments
+}
+
+// layoutSegments combines blocks into one image starting at the lowest
+// origin, zero-filling any gaps. Blocks are copied in the order given, so a
+// later block overwrites any earlier one it overlaps.
+func layoutSegments(blocks []Segment) []byte {
+	if len(blocks) == 0 {
+		return []byte{}
+	}
+	base := int(blocks[0].Origin)
+	for _, block := range blocks {
+		if int(block.Origin) < base {
+			base = int(block.Origin)
+		}
+	}
+	var image []byte
+	for _, block := range blocks {
+		start := int(block.Origin) - base
+		end := start + len(block.Data)
+		for len(image) < end {
+			image = append(image, 0)
+		}
+		copy(image[start:end], block.Data)
+	}
+	return image
+}
+
+// handleSegment processes the .segment "NAME" directive
+func handleSegment(a *Assembler, operand string) error {
+	name := strings.Trim(strings.TrimSpace(operand), "\"")
+	if name == "" {
+		return fmt.Errorf(".segment requires a name")
+	}
+	a.switchSegment(name)
+	return nil
+}
+
+// handleCode processes the .code directive, an alias for .segment "CODE"
+func handleCode(a *Assembler, operand string) error {
+	a.switchSegment(CodeSegment)
+	return nil
+}
+
+// handleData processes the .data directive, an alias for .segment "DATA"
+func handleData(a *Assembler, operand string) error {
+	a.switchSegment(DataSegment)
+	return nil
+}
@@ -0,0 +1,140 @@
+package assembler
+
+import "fmt"
+
+// Charset converts one ASCII character of a string literal to the byte
+// emitted for it
+type Charset func(ch byte) byte
+
+// ascii emits characters unchanged
+func ascii(ch byte) byte {
+	return ch
+}
+
+// petscii maps ASCII to the C64's PETSCII. Lowercase letters become the
+// unshifted codes $41-$5A and uppercase the shifted $C1-$DA, so text reads
+// correctly in the lower/upper case character set.
+func petscii(ch byte) byte {
+	switch {
+	case ch >= 'a' && ch <= 'z':
+		return ch - 'a' + 0x41
+	case ch >= 'A' && ch <= 'Z':
+		return ch - 'A' + 0xC1
+	}
+	return ch
+}
+
+// screenCode maps ASCII to the C64's screen codes, the values stored in
+// screen memory, using the same case convention as petscii.
+func screenCode(ch byte) byte {
+	code := petscii(ch)
+	switch {
+	case code >= 0x40 && code <= 0x5F:
+		return code - 0x40
+	case code >= 0x60 && code <= 0x7F:
+		return code - 0x20
+	case code >= 0xC0 && code <= 0xDF:
+		return code - 0x80
+	}
+	return code
+}
+
+// splitList splits a comma-separated operand list, keeping commas inside
+// string and character literals
+func splitList(operand string) []string {
+	var parts []string
+	start := 0
+	var quote byte
+	for i := 0; i < len(operand); i++ {
+		switch ch := operand[i]; {
+		case ch == '\\' && quote != 0:
+			i++
+		case ch == '"' || ch == '\'':
+			if quote == 0 {
+				quote = ch
+			} else if quote == ch {
+				quote = 0
+			}
+		case ch == ',' && quote == 0:
+			parts = append(parts, operand[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, operand[start:])
+}
+
+// escapes maps the character after a backslash in a literal to its value.
+// Any other escaped character stands for itself, so \" and \\ are a quote
+// and a backslash.
+var escapes = map[byte]byte{
+	'0': 0x00,
+	'n': 0x0A,
+	'r': 0x0D,
+	't': 0x09,
+}
+
+// unescape returns the character at literal[i], decoding a backslash escape,
+// and the index just past it
+func unescape(literal string, i int) (byte, int) {
+	if literal[i] != '\\' || i+1 >= len(literal) {
+		return literal[i], i + 1
+	}
+	if ch, ok := escapes[literal[i+1]]; ok {
+		return ch, i + 2
+	}
+	return literal[i+1], i + 2
+}
+
+// decodeString returns the characters of a double-quoted literal
+func decodeString(literal string) ([]byte, error) {
+	var chars []byte
+	for i := 1; i < len(literal); {
+		if literal[i] == '"' {
+			if i == len(literal)-1 {
+				return chars, nil
+			}
+			return nil, fmt.Errorf("unexpected text after string %s", literal)
+		}
+		var ch byte
+		ch, i = unescape(literal, i)
+		chars = append(chars, ch)
+	}
+	return nil, fmt.Errorf("unterminated string %s", literal)
+}
+
+// handleText processes the .text directive, a synonym for .byte
+func handleText(a *Assembler, operand string) error {
+	return emitByteList(a, operand, ascii, false)
+}
+
+// handleAsciiz processes the .asciiz directive, which appends a 0 byte
+func handleAsciiz(a *Assembler, operand string) error {
+	return emitByteList(a, operand, ascii, true)
+}
+
+// handlePetscii processes the .petscii directive
+func handlePetscii(a *Assembler, operand string) error {
+	return emitByteList(a, operand, petscii, false)
+}
+
+// handleScreen processes the .screen directive
+func handleScreen(a *Assembler, operand string) error {
+	return emitByteList(a, operand, screenCode, false)
+}
+
+// emitByteList assembles a byte list with string literals converted through
+// charset, optionally followed by a 0 terminator
+func emitByteList(a *Assembler, operand string, charset Charset, terminate bool) error {
+	values, err := parseByteList(a, operand, charset)
+	if err != nil {
+		return err
+	}
+	if terminate {
+		values = append(values, 0)
+	}
+	if a.currentPass == 2 {
+		a.output = append(a.output, values...)
+	}
+	a.pc += uint16(len(values))
+	return nil
+}
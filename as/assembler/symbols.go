@@ -0,0 +1,44 @@
+package assembler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SymbolMap returns the address of every label defined by the last
+// assembly. Constants assigned with = or .equ and .repeat counters are left
+// out, as their values are not addresses.
+func (a *Assembler) SymbolMap() map[string]uint16 {
+	symbols := make(map[string]uint16, len(a.symbols))
+	for name, symbol := range a.symbols {
+		if symbol.IsDefined && symbol.IsLabel {
+			symbols[name] = symbol.Value
+		}
+	}
+	return symbols
+}
+
+// WriteViceLabels writes the labels as a VICE monitor label file, one
+// "al C:xxxx .name" line per label, sorted by address and then name. Load
+// it in VICE with -moncommands or the monitor's ll command.
+func (a *Assembler) WriteViceLabels(w io.Writer) error {
+	symbols := a.SymbolMap()
+	names := make([]string, 0, len(symbols))
+	for name := range symbols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if symbols[names[i]] != symbols[names[j]] {
+			return symbols[names[i]] < symbols[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "al C:%04x .%s\n", symbols[name], name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
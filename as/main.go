@@ -4,23 +4,40 @@ import (
 	"flag"
 	"fmt"
 	"github.com/newhook/6502/as/assembler"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
 func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run assembles the file named by the command line args, writing progress
+// and -xref output to stdout
+func run(args []string, stdout io.Writer) error {
 	// Command line flags
-	inputFile := flag.String("i", "", "Input assembly file")
-	outputFile := flag.String("o", "", "Output binary file")
-	listFile := flag.String("l", "", "Generate listing file")
-	flag.Parse()
-	*inputFile = "/Users/matthew/6502/6502/AllSuiteA.asm"
+	flags := flag.NewFlagSet("as", flag.ContinueOnError)
+	inputFile := flags.String("i", "", "Input assembly file")
+	outputFile := flags.String("o", "", "Output binary file")
+	listFile := flags.String("l", "", "Generate listing file")
+	symFile := flags.String("s", "", "Write a VICE label file")
+	xref := flags.Bool("xref", false, "Print symbol cross-references")
+	format := flags.String("f", "bin", "Output format ("+strings.Join(assembler.FormatNames(), ", ")+")")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
 	if *inputFile == "" {
-		fmt.Println("Error: Input file is required")
-		flag.Usage()
-		os.Exit(1)
+		flags.Usage()
+		return fmt.Errorf("Error: Input file is required")
 	}
 
 	// If no output file specified, use input filename with .bin extension
@@ -28,53 +45,116 @@ func main() {
 		*outputFile = strings.TrimSuffix(*inputFile, filepath.Ext(*inputFile)) + ".bin"
 	}
 
-	// Read source file
-	source, err := os.ReadFile(*inputFile)
-	if err != nil {
-		fmt.Printf("Error reading input file: %v\n", err)
-		os.Exit(1)
-	}
-
 	// Create and run assembler
 	as := assembler.NewAssembler()
-	err = as.Assemble(string(source))
+	err := as.AssembleFile(*inputFile)
 	if err != nil {
-		fmt.Printf("Assembly error: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("Assembly error: %v", err)
 	}
 
 	// Write output file
-	err = os.WriteFile(*outputFile, as.GetOutput(), 0644)
+	output, err := assembler.FormatOutput(*format, as.Result())
 	if err != nil {
-		fmt.Printf("Error writing output file: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("Error formatting output: %v", err)
+	}
+	err = os.WriteFile(*outputFile, output, 0644)
+	if err != nil {
+		return fmt.Errorf("Error writing output file: %v", err)
 	}
 
 	// Generate listing file if requested
 	if *listFile != "" {
-		listing := generateListing(string(source), as)
+		listing := generateListing(as)
 		err = os.WriteFile(*listFile, []byte(listing), 0644)
 		if err != nil {
-			fmt.Printf("Error writing listing file: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("Error writing listing file: %v", err)
 		}
 	}
 
-	fmt.Printf("Successfully assembled %s to %s\n", *inputFile, *outputFile)
-	fmt.Printf("Output size: %d bytes\n", len(as.GetOutput()))
+	if *symFile != "" {
+		labels, err := os.Create(*symFile)
+		if err != nil {
+			return fmt.Errorf("Error writing label file: %v", err)
+		}
+		err = as.WriteViceLabels(labels)
+		if closeErr := labels.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("Error writing label file: %v", err)
+		}
+	}
+
+	if *xref {
+		fmt.Fprint(stdout, generateCrossReferences(as))
+	}
+
+	fmt.Fprintf(stdout, "Successfully assembled %s to %s\n", *inputFile, *outputFile)
+	fmt.Fprintf(stdout, "Output size: %d bytes\n", len(as.GetOutput()))
+	return nil
 }
 
-func generateListing(source string, as *assembler.Assembler) string {
+// generateListing formats the assembler's listing as address, bytes and
+// source columns. Lines emitting more than three bytes continue on following
+// rows.
+func generateListing(as *assembler.Assembler) string {
 	var listing strings.Builder
-	lines := strings.Split(source, "\n")
-	addr := uint16(0)
-
-	for _, line := range lines {
-		listing.WriteString(fmt.Sprintf("%04X  %-32s %s\n", addr, line, ""))
-		// Note: This is a simplified listing - a full implementation would
-		// need to track addresses and bytes more accurately
-		addr += 1
+
+	for _, entry := range as.Listing() {
+		if len(entry.Bytes) == 0 {
+			listing.WriteString(strings.TrimRight(fmt.Sprintf("%04X  %-8s  %s", entry.Addr, "", entry.Source), " ") + "\n")
+			continue
+		}
+		for i := 0; i < len(entry.Bytes); i += 3 {
+			end := i + 3
+			if end > len(entry.Bytes) {
+				end = len(entry.Bytes)
+			}
+			hex := make([]string, 0, 3)
+			for _, b := range entry.Bytes[i:end] {
+				hex = append(hex, fmt.Sprintf("%02X", b))
+			}
+			source := ""
+			if i == 0 {
+				source = entry.Source
+			}
+			listing.WriteString(strings.TrimRight(fmt.Sprintf("%04X  %-8s  %s", int(entry.Addr)+i, strings.Join(hex, " "), source), " ") + "\n")
+		}
 	}
 
 	return listing.String()
 }
+
+func generateCrossReferences(as *assembler.Assembler) string {
+	refs := as.CrossReferences()
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		symbol, exists := as.LookupSymbol(name)
+		if !exists {
+			out.WriteString(fmt.Sprintf("%-16s undefined  used %s\n", name, joinLines(refs[name])))
+			continue
+		}
+		var uses []int
+		for _, line := range refs[name] {
+			if line != symbol.Line {
+				uses = append(uses, line)
+			}
+		}
+		out.WriteString(fmt.Sprintf("%-16s $%04X  defined %d  used %s\n", name, symbol.Value, symbol.Line, joinLines(uses)))
+	}
+	return out.String()
+}
+
+func joinLines(lines []int) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		parts[i] = fmt.Sprint(line)
+	}
+	return strings.Join(parts, ", ")
+}
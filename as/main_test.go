@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/newhook/6502/as/assembler"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunFlags(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "tiny.asm")
+	assert.NoError(t, os.WriteFile(input, []byte(".org $C000\nstart:\n\tLDA #$01\n\tJMP start\n"), 0644))
+
+	var stdout bytes.Buffer
+	assert.NoError(t, run([]string{"-i", input}, &stdout))
+	output, err := os.ReadFile(filepath.Join(dir, "tiny.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xA9, 0x01, 0x4C, 0x00, 0xC0}, output)
+	assert.Contains(t, stdout.String(), "Successfully assembled "+input)
+
+	assembler.RegisterFormat("hex", assembler.FormatterFunc(func(result assembler.AsmResult) ([]byte, error) {
+		return []byte(fmt.Sprintf("% X", result.Output)), nil
+	}))
+	hex := filepath.Join(dir, "tiny.hex")
+	labels := filepath.Join(dir, "tiny.lbl")
+	stdout.Reset()
+	assert.NoError(t, run([]string{"-i", input, "-o", hex, "-f", "hex", "-s", labels, "-xref"}, &stdout))
+	output, err = os.ReadFile(hex)
+	assert.NoError(t, err)
+	assert.Equal(t, "A9 01 4C 00 C0", string(output))
+	output, err = os.ReadFile(labels)
+	assert.NoError(t, err)
+	assert.Equal(t, "al C:c000 .start\n", string(output))
+	assert.Contains(t, stdout.String(), "start            $C000  defined 2  used 4\n")
+
+	err = run([]string{"-i", input, "-f", "nope"}, &stdout)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unknown output format "nope"`)
+	}
+	assert.Error(t, run(nil, &stdout), "-i is required")
+}
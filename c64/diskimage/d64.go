@@ -0,0 +1,161 @@
+package diskimage
+
+import (
+	"fmt"
+)
+
+// D64 geometry
+const (
+	sectorSize     = 256
+	directoryTrack = 18
+	d64Sectors35   = 683 // Sectors on a standard 35 track disk
+	d64Sectors40   = 768 // Sectors on an extended 40 track disk
+	entrySize      = 32
+)
+
+// D64 is a 1541 disk image
+type D64 struct {
+	Name   string // Disk name from the BAM
+	ID     string // Two-character disk ID
+	data   []byte
+	tracks int
+	files  []d64Entry
+}
+
+// d64Entry is a directory entry with the start of its sector chain
+type d64Entry struct {
+	File
+	track, sector uint8
+}
+
+// OpenD64 parses a D64 image of 35 or 40 tracks, with or without the
+// trailing per-sector error bytes
+func OpenD64(data []byte) (*D64, error) {
+	d := &D64{data: data}
+	switch len(data) {
+	case d64Sectors35 * sectorSize, d64Sectors35 * (sectorSize + 1):
+		d.tracks = 35
+	case d64Sectors40 * sectorSize, d64Sectors40 * (sectorSize + 1):
+		d.tracks = 40
+	default:
+		return nil, fmt.Errorf("%d bytes is not a D64 image size", len(data))
+	}
+
+	bam, err := d.sector(directoryTrack, 0)
+	if err != nil {
+		return nil, err
+	}
+	d.Name = petsciiName(bam[0x90:0xA0])
+	d.ID = string(bam[0xA2:0xA4])
+
+	if err := d.readDirectory(bam[0], bam[1]); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// sectorsPerTrack returns how many sectors the 1541 writes on track,
+// fewer towards the hub
+func sectorsPerTrack(track int) int {
+	switch {
+	case track <= 17:
+		return 21
+	case track <= 24:
+		return 19
+	case track <= 30:
+		return 18
+	}
+	return 17
+}
+
+// sector returns the 256 bytes of track (from 1) and sector (from 0)
+func (d *D64) sector(track, sector uint8) ([]byte, error) {
+	if track < 1 || int(track) > d.tracks || int(sector) >= sectorsPerTrack(int(track)) {
+		return nil, fmt.Errorf("track %d sector %d is off the disk", track, sector)
+	}
+	offset := 0
+	for t := 1; t < int(track); t++ {
+		offset += sectorsPerTrack(t)
+	}
+	offset = (offset + int(sector)) * sectorSize
+	return d.data[offset : offset+sectorSize], nil
+}
+
+// chain follows the sector links from track and sector, calling visit with
+// each sector's payload. The last sector's link holds the index of its last
+// used byte instead of a sector number.
+func (d *D64) chain(track, sector uint8, visit func([]byte)) error {
+	for visited := 0; track != 0; visited++ {
+		if visited > d64Sectors40 {
+			return fmt.Errorf("sector chain from track %d sector %d loops", track, sector)
+		}
+		data, err := d.sector(track, sector)
+		if err != nil {
+			return err
+		}
+		end := sectorSize
+		if data[0] == 0 {
+			end = int(data[1]) + 1
+			if end < 2 {
+				end = 2
+			}
+		}
+		visit(data[2:end])
+		track, sector = data[0], data[1]
+	}
+	return nil
+}
+
+// readDirectory walks the directory sectors, keeping every entry that has
+// not been scratched
+func (d *D64) readDirectory(track, sector uint8) error {
+	for visited := 0; track != 0; visited++ {
+		if visited > sectorsPerTrack(directoryTrack) {
+			return fmt.Errorf("directory chain loops")
+		}
+		data, err := d.sector(track, sector)
+		if err != nil {
+			return fmt.Errorf("directory: %w", err)
+		}
+		for offset := 0; offset < sectorSize; offset += entrySize {
+			entry := data[offset : offset+entrySize]
+			if entry[2] == 0 {
+				continue
+			}
+			e := d64Entry{
+				File: File{
+					Name: petsciiName(entry[0x05:0x15]),
+					Type: FileType(entry[2] & 0x07),
+				},
+				track:  entry[3],
+				sector: entry[4],
+			}
+			if err := d.chain(e.track, e.sector, func(b []byte) { e.Size += len(b) }); err != nil {
+				return fmt.Errorf("%s: %w", e.Name, err)
+			}
+			d.files = append(d.files, e)
+		}
+		track, sector = data[0], data[1]
+	}
+	return nil
+}
+
+func (d *D64) ListFiles() []File {
+	files := make([]File, len(d.files))
+	for i, e := range d.files {
+		files[i] = e.File
+	}
+	return files
+}
+
+func (d *D64) ReadFile(name string) ([]byte, error) {
+	for _, e := range d.files {
+		if !matchName(name, e.Name) {
+			continue
+		}
+		data := make([]byte, 0, e.Size)
+		err := d.chain(e.track, e.sector, func(b []byte) { data = append(data, b...) })
+		return data, err
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrFileNotFound)
+}
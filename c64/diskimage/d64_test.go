@@ -0,0 +1,126 @@
+package diskimage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// d64Builder lays out a blank 35 track image sector by sector
+type d64Builder []byte
+
+func newD64Builder() d64Builder {
+	return make(d64Builder, d64Sectors35*sectorSize)
+}
+
+func (b d64Builder) sector(track, sector uint8) []byte {
+	offset := 0
+	for t := 1; t < int(track); t++ {
+		offset += sectorsPerTrack(t)
+	}
+	offset = (offset + int(sector)) * sectorSize
+	return b[offset : offset+sectorSize]
+}
+
+// name pads s with shifted spaces as CBM DOS does
+func name(s string, size int) []byte {
+	padded := []byte(s)
+	for len(padded) < size {
+		padded = append(padded, 0xA0)
+	}
+	return padded
+}
+
+// tinyD64 holds HELLO, a PRG spanning two sectors, and a scratched file
+func tinyD64() ([]byte, []byte) {
+	b := newD64Builder()
+
+	bam := b.sector(18, 0)
+	bam[0], bam[1], bam[2] = 18, 1, 'A'
+	copy(bam[0x90:], name("TEST DISK", 16))
+	copy(bam[0xA2:], "ID")
+
+	program := make([]byte, 300)
+	program[0], program[1] = 0x01, 0x08 // Load address $0801
+	for i := 2; i < len(program); i++ {
+		program[i] = uint8(i)
+	}
+
+	dir := b.sector(18, 1)
+	dir[0], dir[1] = 0, 0xFF
+	hello := dir[0:entrySize]
+	hello[2], hello[3], hello[4] = 0x82, 17, 0 // Closed PRG at 17/0
+	copy(hello[5:], name("HELLO", 16))
+	hello[0x1E] = 2
+	scratched := dir[entrySize : 2*entrySize]
+	scratched[2], scratched[3], scratched[4] = 0x00, 17, 5
+	copy(scratched[5:], name("GONE", 16))
+
+	first := b.sector(17, 0)
+	first[0], first[1] = 17, 10
+	copy(first[2:], program[:254])
+	last := b.sector(17, 10)
+	rest := program[254:]
+	last[0], last[1] = 0, uint8(1+len(rest))
+	copy(last[2:], rest)
+
+	return b, program
+}
+
+func TestD64(t *testing.T) {
+	data, program := tinyD64()
+	d, err := OpenD64(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "TEST DISK", d.Name)
+	assert.Equal(t, "ID", d.ID)
+	assert.Equal(t, []File{{Name: "HELLO", Type: PRG, Size: 300}}, d.ListFiles())
+
+	for _, pattern := range []string{"HELLO", "HE*", "H?LLO", "*"} {
+		prg, err := d.ReadFile(pattern)
+		assert.NoError(t, err, pattern)
+		assert.Equal(t, program, prg, pattern)
+	}
+
+	for _, pattern := range []string{"HELL", "HELLO2", "GONE"} {
+		_, err = d.ReadFile(pattern)
+		assert.True(t, errors.Is(err, ErrFileNotFound), pattern)
+	}
+
+	image, err := Open(data)
+	assert.NoError(t, err)
+	assert.IsType(t, &D64{}, image)
+}
+
+func TestD64Errors(t *testing.T) {
+	_, err := OpenD64(make([]byte, 1000))
+	assert.Error(t, err, "wrong size")
+
+	// A chain pointing back at itself must not hang
+	data, _ := tinyD64()
+	b := d64Builder(data)
+	b.sector(17, 10)[0], b.sector(17, 10)[1] = 17, 0
+	_, err = OpenD64(data)
+	assert.Error(t, err)
+
+	// Links off the disk are reported
+	data, _ = tinyD64()
+	b = d64Builder(data)
+	b.sector(17, 0)[0] = 36
+	_, err = OpenD64(data)
+	assert.Error(t, err)
+}
+
+func TestSectorsPerTrack(t *testing.T) {
+	total := 0
+	for track := 1; track <= 35; track++ {
+		total += sectorsPerTrack(track)
+	}
+	assert.Equal(t, d64Sectors35, total)
+	for track := 36; track <= 40; track++ {
+		total += sectorsPerTrack(track)
+	}
+	assert.Equal(t, d64Sectors40, total)
+}
@@ -0,0 +1,87 @@
+// Package diskimage reads the D64 disk and T64 tape archives most C64
+// software ships in, extracting their files as PRG byte slices.
+package diskimage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFileNotFound is returned by ReadFile when no file matches the name
+var ErrFileNotFound = errors.New("file not found")
+
+// FileType is the CBM DOS type of a directory entry
+type FileType uint8
+
+const (
+	DEL FileType = iota
+	SEQ
+	PRG
+	USR
+	REL
+)
+
+func (t FileType) String() string {
+	switch t {
+	case DEL:
+		return "DEL"
+	case SEQ:
+		return "SEQ"
+	case PRG:
+		return "PRG"
+	case USR:
+		return "USR"
+	case REL:
+		return "REL"
+	}
+	return fmt.Sprintf("?%d", uint8(t))
+}
+
+// File is one directory entry
+type File struct {
+	Name string
+	Type FileType
+	Size int // Length in bytes of what ReadFile returns
+}
+
+// Image is a disk or tape image holding files
+type Image interface {
+	// ListFiles returns the directory in image order
+	ListFiles() []File
+	// ReadFile returns the contents of the first file matching name. For a
+	// PRG this starts with its two-byte load address.
+	ReadFile(name string) ([]byte, error)
+}
+
+// Open reads a T64 archive when data carries the T64 signature and a D64
+// disk image otherwise
+func Open(data []byte) (Image, error) {
+	if isT64(data) {
+		return OpenT64(data)
+	}
+	return OpenD64(data)
+}
+
+// petsciiName converts a directory name, dropping the shifted-space padding
+// CBM DOS fills names with
+func petsciiName(raw []byte) string {
+	if i := strings.IndexByte(string(raw), 0xA0); i >= 0 {
+		raw = raw[:i]
+	}
+	return string(raw)
+}
+
+// matchName reports whether a file name matches pattern. As with the 1541,
+// a trailing * matches any remainder and ? matches any one character.
+func matchName(pattern, name string) bool {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '*' {
+			return true
+		}
+		if i >= len(name) || (pattern[i] != '?' && pattern[i] != name[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(name)
+}
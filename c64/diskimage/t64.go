@@ -0,0 +1,97 @@
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// T64 layout
+const (
+	t64HeaderSize = 0x40
+	t64EntrySize  = 0x20
+)
+
+// T64 is a tape archive of files, each stored with its load address
+type T64 struct {
+	Name  string // Tape name from the header
+	data  []byte
+	files []t64Entry
+}
+
+type t64Entry struct {
+	File
+	start  uint16
+	offset int
+}
+
+// isT64 reports whether data starts with the "C64" signature every T64
+// variant ("C64 tape image file", "C64S tape file", ...) shares
+func isT64(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("C64"))
+}
+
+// OpenT64 parses a T64 archive. Many tools wrote a bad end address into
+// the directory, so a file's length is capped at the data that follows it.
+func OpenT64(data []byte) (*T64, error) {
+	if !isT64(data) || len(data) < t64HeaderSize {
+		return nil, fmt.Errorf("not a T64 archive")
+	}
+	t := &T64{
+		Name: string(bytes.TrimRight(data[0x28:0x40], " \x00\xA0")),
+		data: data,
+	}
+
+	entries := int(binary.LittleEndian.Uint16(data[0x22:]))
+	for i := 0; i < entries; i++ {
+		offset := t64HeaderSize + i*t64EntrySize
+		if offset+t64EntrySize > len(data) {
+			return nil, fmt.Errorf("directory entry %d is past the end of the archive", i)
+		}
+		entry := data[offset : offset+t64EntrySize]
+		if entry[0] != 1 {
+			continue // Free slot or memory snapshot
+		}
+		e := t64Entry{
+			File: File{
+				Name: string(bytes.TrimRight(entry[0x10:0x20], " \xA0")),
+				Type: FileType(entry[1] & 0x07),
+			},
+			start:  binary.LittleEndian.Uint16(entry[0x02:]),
+			offset: int(binary.LittleEndian.Uint32(entry[0x08:])),
+		}
+		if e.offset > len(data) {
+			return nil, fmt.Errorf("%s: data offset $%X is past the end of the archive", e.Name, e.offset)
+		}
+		length := int(binary.LittleEndian.Uint16(entry[0x04:])) - int(e.start)
+		if length < 0 || e.offset+length > len(data) {
+			length = len(data) - e.offset
+		}
+		e.Size = 2 + length
+		if e.Type == DEL {
+			e.Type = PRG // Older archivers left the type byte clear
+		}
+		t.files = append(t.files, e)
+	}
+	return t, nil
+}
+
+func (t *T64) ListFiles() []File {
+	files := make([]File, len(t.files))
+	for i, e := range t.files {
+		files[i] = e.File
+	}
+	return files
+}
+
+func (t *T64) ReadFile(name string) ([]byte, error) {
+	for _, e := range t.files {
+		if !matchName(name, e.Name) {
+			continue
+		}
+		prg := make([]byte, 0, e.Size)
+		prg = binary.LittleEndian.AppendUint16(prg, e.start)
+		return append(prg, t.data[e.offset:e.offset+e.Size-2]...), nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrFileNotFound)
+}
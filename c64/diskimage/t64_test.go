@@ -0,0 +1,67 @@
+package diskimage
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// tinyT64 builds an archive with two directory slots: GAME, stored with an
+// end address past the data as many archivers wrote it, and a free slot
+func tinyT64() ([]byte, []byte) {
+	code := []byte{0xA9, 0x01, 0x8D, 0x20, 0xD0, 0x60}
+	data := make([]byte, t64HeaderSize+2*t64EntrySize)
+	copy(data, "C64S tape file")
+	binary.LittleEndian.PutUint16(data[0x20:], 0x0101)
+	binary.LittleEndian.PutUint16(data[0x22:], 2)
+	binary.LittleEndian.PutUint16(data[0x24:], 1)
+	copy(data[0x28:], "TEST TAPE               ")
+
+	entry := data[t64HeaderSize:]
+	entry[0], entry[1] = 1, 0x82
+	binary.LittleEndian.PutUint16(entry[0x02:], 0xC000)
+	binary.LittleEndian.PutUint16(entry[0x04:], 0xC3C6)
+	binary.LittleEndian.PutUint32(entry[0x08:], uint32(len(data)))
+	copy(entry[0x10:], "GAME            ")
+
+	prg := append([]byte{0x00, 0xC0}, code...)
+	return append(data, code...), prg
+}
+
+func TestT64(t *testing.T) {
+	data, prg := tinyT64()
+	tape, err := OpenT64(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "TEST TAPE", tape.Name)
+	assert.Equal(t, []File{{Name: "GAME", Type: PRG, Size: len(prg)}}, tape.ListFiles())
+
+	got, err := tape.ReadFile("GAME")
+	assert.NoError(t, err)
+	assert.Equal(t, prg, got)
+
+	_, err = tape.ReadFile("OTHER")
+	assert.True(t, errors.Is(err, ErrFileNotFound))
+
+	image, err := Open(data)
+	assert.NoError(t, err)
+	assert.IsType(t, &T64{}, image)
+}
+
+func TestT64Errors(t *testing.T) {
+	_, err := OpenT64([]byte("C64"))
+	assert.Error(t, err, "truncated header")
+
+	data, _ := tinyT64()
+	binary.LittleEndian.PutUint16(data[0x22:], 10)
+	_, err = OpenT64(data)
+	assert.Error(t, err, "directory past the end")
+
+	data, _ = tinyT64()
+	binary.LittleEndian.PutUint32(data[t64HeaderSize+0x08:], 0xFFFF)
+	_, err = OpenT64(data)
+	assert.Error(t, err, "data offset past the end")
+}
@@ -1,6 +1,7 @@
 package cpu
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -337,3 +338,40 @@ func TestADCDecimalMode(t *testing.T) {
 		})
 	}
 }
+
+// TestADCDecimalFlags checks the NMOS decimal mode flags against the
+// examples in Bruce Clark's decimal mode tutorial: Z from the binary sum,
+// N and V from the sum with only the low digit adjusted
+func TestADCDecimalFlags(t *testing.T) {
+	tests := []struct {
+		a, value uint8
+		carry    bool
+		wantA    uint8
+		wantP    uint8 // N, V, Z and C; D stays set
+	}{
+		{a: 0x00, value: 0x00, wantA: 0x00, wantP: FlagZ},
+		{a: 0x79, value: 0x00, carry: true, wantA: 0x80, wantP: FlagN | FlagV},
+		{a: 0x24, value: 0x56, wantA: 0x80, wantP: FlagN | FlagV},
+		{a: 0x93, value: 0x82, wantA: 0x75, wantP: FlagV | FlagC},
+		{a: 0x89, value: 0x76, wantA: 0x65, wantP: FlagC},
+		{a: 0x99, value: 0x01, wantA: 0x00, wantP: FlagN | FlagC}, // Z clear: binary sum is $9A
+		{a: 0x80, value: 0x80, wantA: 0x60, wantP: FlagV | FlagZ | FlagC},
+		{a: 0x0F, value: 0x0F, wantA: 0x14}, // Invalid BCD
+		{a: 0x58, value: 0x46, carry: true, wantA: 0x05, wantP: FlagN | FlagV | FlagC},
+	}
+
+	for _, tt := range tests {
+		c := NewCPUAndMemory()
+		c.A = tt.a
+		c.P = FlagD
+		if tt.carry {
+			c.P |= FlagC
+		}
+		c.Memory[0] = tt.value
+		c.execute(ADC_IMM)
+
+		name := fmt.Sprintf("$%02X+$%02X+%t", tt.a, tt.value, tt.carry)
+		assert.Equal(t, tt.wantA, c.A, name)
+		assert.Equal(t, tt.wantP|FlagD, c.P, name)
+	}
+}
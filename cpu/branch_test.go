@@ -170,3 +170,37 @@ func TestBranchInstructions(t *testing.T) {
 		})
 	}
 }
+
+// TestBranchPageCrossBase pins the page-cross penalty to the address of the
+// instruction after the branch, not the branch or its operand byte.
+func TestBranchPageCrossBase(t *testing.T) {
+	tests := []struct {
+		name     string
+		branchAt uint16
+		offset   int8
+		expectPC uint16
+		cycles   uint8
+	}{
+		{"next and target on same page past operand page", 0x10FE, 2, 0x1102, 3},
+		{"target back on operand page", 0x10FE, -1, 0x10FF, 4},
+		{"forward across page", 0x10FD, 1, 0x1100, 4},
+		{"forward within page", 0x10FC, 1, 0x10FF, 3},
+		{"backward across page", 0x1100, -3, 0x10FF, 4},
+		{"backward within page", 0x1100, -2, 0x1100, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCPUAndMemory()
+			c.Memory[tt.branchAt] = BNE
+			c.Memory[tt.branchAt+1] = uint8(tt.offset)
+			c.PC = tt.branchAt
+			c.P = 0x20 // Z clear
+
+			cycles := c.Step()
+
+			assert.Equal(t, tt.expectPC, c.PC)
+			assert.Equal(t, tt.cycles, cycles)
+		})
+	}
+}
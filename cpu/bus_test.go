@@ -0,0 +1,212 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// busAccess records a single read or write seen by spyBus
+type busAccess struct {
+	Addr  uint16
+	Value uint8
+	Write bool
+}
+
+// spyBus is a flat 64K memory that records every access made through it
+type spyBus struct {
+	Memory   [65536]uint8
+	Accesses []busAccess
+}
+
+func (b *spyBus) Read(address uint16) uint8 {
+	value := b.Memory[address]
+	b.Accesses = append(b.Accesses, busAccess{Addr: address, Value: value})
+	return value
+}
+
+func (b *spyBus) Write(address uint16, value uint8) {
+	b.Memory[address] = value
+	b.Accesses = append(b.Accesses, busAccess{Addr: address, Value: value, Write: true})
+}
+
+// Peek reads without recording an access
+func (b *spyBus) Peek(address uint16) uint8 {
+	return b.Memory[address]
+}
+
+// reads returns the addresses read, in order
+func (b *spyBus) reads() []uint16 {
+	var addrs []uint16
+	for _, a := range b.Accesses {
+		if !a.Write {
+			addrs = append(addrs, a.Addr)
+		}
+	}
+	return addrs
+}
+
+func TestIndexedDummyRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		setup   func(*CPU, *spyBus)
+		reads   []uint16
+		cycles  uint8
+	}{
+		{
+			name:    "LDA absolute,X page cross",
+			program: []uint8{LDA_ABX, 0xFF, 0x12},
+			setup:   func(c *CPU, _ *spyBus) { c.X = 0x01 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0x1200, 0x1300},
+			cycles:  5,
+		},
+		{
+			name:    "LDA absolute,X no page cross",
+			program: []uint8{LDA_ABX, 0x10, 0x12},
+			setup:   func(c *CPU, _ *spyBus) { c.X = 0x01 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0x1211},
+			cycles:  4,
+		},
+		{
+			name:    "LDX absolute,Y page cross",
+			program: []uint8{LDX_ABY, 0x80, 0xD0},
+			setup:   func(c *CPU, _ *spyBus) { c.Y = 0x90 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0xD010, 0xD110},
+			cycles:  5,
+		},
+		{
+			name:    "LDA (indirect),Y page cross",
+			program: []uint8{LDA_INY, 0x40},
+			setup: func(c *CPU, b *spyBus) {
+				b.Memory[0x40] = 0xF0
+				b.Memory[0x41] = 0xDC
+				c.Y = 0x20
+			},
+			reads:  []uint16{0x1000, 0x1001, 0x0040, 0x0041, 0xDC10, 0xDD10},
+			cycles: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &spyBus{}
+			c := NewCPU(bus)
+			copy(bus.Memory[0x1000:], tt.program)
+			c.PC = 0x1000
+			tt.setup(c, bus)
+
+			cycles := c.Step()
+
+			assert.Equal(t, tt.cycles, cycles)
+			assert.Equal(t, tt.reads, bus.reads())
+		})
+	}
+}
+
+// writes returns the write accesses, in order
+func (b *spyBus) writes() []busAccess {
+	var accesses []busAccess
+	for _, a := range b.Accesses {
+		if a.Write {
+			accesses = append(accesses, a)
+		}
+	}
+	return accesses
+}
+
+func TestIndirectStoresUseBus(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		setup   func(*CPU, *spyBus)
+		write   busAccess
+	}{
+		{
+			name:    "STA (indirect),Y",
+			program: []uint8{STA_INY, 0x40},
+			setup: func(c *CPU, b *spyBus) {
+				b.Memory[0x40] = 0x00
+				b.Memory[0x41] = 0xD0
+				c.Y = 0x20
+			},
+			write: busAccess{Addr: 0xD020, Value: 0x42, Write: true},
+		},
+		{
+			name:    "STA (indirect),Y pointer wraps in zero page",
+			program: []uint8{STA_INY, 0xFF},
+			setup: func(c *CPU, b *spyBus) {
+				b.Memory[0xFF] = 0x10
+				b.Memory[0x00] = 0x20
+				b.Memory[0x100] = 0x30 // Must not be used as the high byte
+				c.Y = 0x05
+			},
+			write: busAccess{Addr: 0x2015, Value: 0x42, Write: true},
+		},
+		{
+			name:    "STA (indirect,X)",
+			program: []uint8{STA_INX, 0x40},
+			setup: func(c *CPU, b *spyBus) {
+				b.Memory[0x44] = 0x18
+				b.Memory[0x45] = 0xD4
+				c.X = 0x04
+			},
+			write: busAccess{Addr: 0xD418, Value: 0x42, Write: true},
+		},
+		{
+			name:    "STA (indirect,X) index wraps in zero page",
+			program: []uint8{STA_INX, 0xF0},
+			setup: func(c *CPU, b *spyBus) {
+				b.Memory[0x10] = 0x00
+				b.Memory[0x11] = 0x04
+				c.X = 0x20
+			},
+			write: busAccess{Addr: 0x0400, Value: 0x42, Write: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &spyBus{}
+			c := NewCPU(bus)
+			copy(bus.Memory[0x1000:], tt.program)
+			c.PC = 0x1000
+			c.A = 0x42
+			tt.setup(c, bus)
+
+			cycles := c.Step()
+
+			assert.Equal(t, uint8(6), cycles)
+			assert.Equal(t, []busAccess{tt.write}, bus.writes())
+		})
+	}
+}
+
+func TestReadModifyWriteUsesBus(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		addr    uint16
+		value   uint8
+		result  uint8
+	}{
+		{"INC absolute", []uint8{INC_ABS, 0x20, 0xD0}, 0xD020, 0x0E, 0x0F},
+		{"DEC zero page", []uint8{DEC_ZP, 0x40}, 0x0040, 0x00, 0xFF},
+		{"ASL absolute", []uint8{ASL_ABS, 0x00, 0x04}, 0x0400, 0x41, 0x82},
+		{"ROL zero page", []uint8{ROL_ZP, 0x40}, 0x0040, 0x80, 0x00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &spyBus{}
+			c := NewCPU(bus)
+			copy(bus.Memory[0x1000:], tt.program)
+			bus.Memory[tt.addr] = tt.value
+			c.PC = 0x1000
+
+			c.Step()
+
+			assert.Contains(t, bus.reads(), tt.addr)
+			assert.Equal(t, []busAccess{{Addr: tt.addr, Value: tt.result, Write: true}}, bus.writes())
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package cpu
+
+// Variant identifies the member of the 6502 family a CPU emulates
+type Variant int
+
+const (
+	NMOS6502  Variant = iota // The original NMOS 6502, as in the 6510
+	CMOS65C02                // The CMOS 65C02, with WDC's WAI and STP
+)
+
+// cmos reports whether the CPU is a 65C02
+func (c *CPU) cmos() bool {
+	return c.Variant == CMOS65C02
+}
+
+// 65C02 opcodes, only decoded when CPU.Variant is CMOS65C02. They reuse slots
+// that are undocumented on the NMOS 6502.
+const (
+	BRA = 0x80
+
+	PHX = 0xDA
+	PHY = 0x5A
+	PLX = 0xFA
+	PLY = 0x7A
+
+	STZ_ZP  = 0x64
+	STZ_ZPX = 0x74
+	STZ_ABS = 0x9C
+	STZ_ABX = 0x9E
+
+	TSB_ZP  = 0x04
+	TSB_ABS = 0x0C
+	TRB_ZP  = 0x14
+	TRB_ABS = 0x1C
+
+	BIT_IMM = 0x89
+	BIT_ZPX = 0x34
+	BIT_ABX = 0x3C
+
+	INC_ACC = 0x1A
+	DEC_ACC = 0x3A
+
+	JMP_IAX = 0x7C
+
+	// Zero page indirect, (zp)
+	ORA_IZP = 0x12
+	AND_IZP = 0x32
+	EOR_IZP = 0x52
+	ADC_IZP = 0x72
+	STA_IZP = 0x92
+	LDA_IZP = 0xB2
+	CMP_IZP = 0xD2
+	SBC_IZP = 0xF2
+)
+
+// executeCMOS runs a 65C02 opcode, reporting false if it is not one
+func (c *CPU) executeCMOS(opcode uint8) (uint8, bool) {
+	switch opcode {
+	case BRA:
+		return c.branch(true), true
+
+	case PHX:
+		c.push(c.X)
+		return 3, true
+	case PHY:
+		c.push(c.Y)
+		return 3, true
+	case PLX:
+		c.X = c.pull()
+		c.updateZN(c.X)
+		return 4, true
+	case PLY:
+		c.Y = c.pull()
+		c.updateZN(c.Y)
+		return 4, true
+
+	case STZ_ZP:
+		c.Write(uint16(c.readImmediate()), 0)
+		return 3, true
+	case STZ_ZPX:
+		c.Write(uint16(c.readImmediate()+c.X), 0)
+		return 4, true
+	case STZ_ABS:
+		c.Write(c.readAbsoluteAddress(), 0)
+		return 4, true
+	case STZ_ABX:
+		c.Write(c.readAbsoluteAddress()+uint16(c.X), 0)
+		return 5, true
+
+	case TSB_ZP:
+		c.tsb(uint16(c.readImmediate()))
+		return 5, true
+	case TSB_ABS:
+		c.tsb(c.readAbsoluteAddress())
+		return 6, true
+	case TRB_ZP:
+		c.trb(uint16(c.readImmediate()))
+		return 5, true
+	case TRB_ABS:
+		c.trb(c.readAbsoluteAddress())
+		return 6, true
+
+	case BIT_IMM:
+		// Only Z, as there is no memory operand for N and V to come from
+		c.testBits(c.readImmediate())
+		return 2, true
+	case BIT_ZPX:
+		c.bit(c.readZeroPageX())
+		return 4, true
+	case BIT_ABX:
+		value, pageCrossed := c.readAbsoluteX()
+		c.bit(value)
+		if pageCrossed {
+			return 5, true
+		}
+		return 4, true
+
+	case INC_ACC:
+		c.A++
+		c.updateZN(c.A)
+		return 2, true
+	case DEC_ACC:
+		c.A--
+		c.updateZN(c.A)
+		return 2, true
+
+	case JMP_IAX:
+		addr := c.readAbsoluteAddress() + uint16(c.X)
+		c.PC = uint16(c.Read(addr)) | uint16(c.Read(addr+1))<<8
+		return 6, true
+
+	case ORA_IZP:
+		c.A |= c.readIndirectZeroPage()
+		c.updateZN(c.A)
+		return 5, true
+	case AND_IZP:
+		c.A &= c.readIndirectZeroPage()
+		c.updateZN(c.A)
+		return 5, true
+	case EOR_IZP:
+		c.A ^= c.readIndirectZeroPage()
+		c.updateZN(c.A)
+		return 5, true
+	case ADC_IZP:
+		c.adc(c.readIndirectZeroPage())
+		return 5, true
+	case STA_IZP:
+		c.Write(c.readIndirectAddress(c.readImmediate()), c.A)
+		return 5, true
+	case LDA_IZP:
+		c.A = c.readIndirectZeroPage()
+		c.updateZN(c.A)
+		return 5, true
+	case CMP_IZP:
+		c.cmp(c.readIndirectZeroPage())
+		return 5, true
+	case SBC_IZP:
+		c.sbc(c.readIndirectZeroPage())
+		return 5, true
+	}
+	return 0, false
+}
+
+// decimalPenalty reports whether opcode is an ADC or SBC, which take an
+// extra cycle on the 65C02 in decimal mode to produce valid N and Z flags
+func decimalPenalty(opcode uint8) bool {
+	switch opcode {
+	case ADC_IMM, ADC_ZP, ADC_ZPX, ADC_ABS, ADC_ABX, ADC_ABY, ADC_INX, ADC_INY, ADC_IZP,
+		SBC_IMM, SBC_ZP, SBC_ZPX, SBC_ABS, SBC_ABX, SBC_ABY, SBC_INX, SBC_INY, SBC_IZP:
+		return true
+	}
+	return false
+}
+
+// readIndirectZeroPage reads through the pointer at the zero page operand
+func (c *CPU) readIndirectZeroPage() uint8 {
+	return c.Read(c.readIndirectAddress(c.readImmediate()))
+}
+
+// bit sets Z from A AND value, and N and V from bits 7 and 6 of value
+func (c *CPU) bit(value uint8) {
+	c.P &^= FlagN | FlagV
+	c.P |= value & (FlagN | FlagV)
+	c.testBits(value)
+}
+
+// tsb sets the bits of A in memory, with Z from A AND the old value
+func (c *CPU) tsb(addr uint16) {
+	value := c.Read(addr)
+	c.testBits(value)
+	c.Write(addr, value|c.A)
+}
+
+// trb clears the bits of A in memory, with Z from A AND the old value
+func (c *CPU) trb(addr uint16) {
+	value := c.Read(addr)
+	c.testBits(value)
+	c.Write(addr, value&^c.A)
+}
+
+// testBits sets Z from A AND value, leaving the other flags alone
+func (c *CPU) testBits(value uint8) {
+	if c.A&value == 0 {
+		c.P |= FlagZ
+	} else {
+		c.P &^= FlagZ
+	}
+}
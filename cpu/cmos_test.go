@@ -0,0 +1,245 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newCMOSTestCPU returns a 65C02 with program loaded at $1000
+func newCMOSTestCPU(program ...uint8) *CPUAndMemory {
+	c := NewCPUAndMemory()
+	c.Variant = CMOS65C02
+	copy(c.Memory[0x1000:], program)
+	c.PC = 0x1000
+	return c
+}
+
+func TestBRA(t *testing.T) {
+	tests := []struct {
+		name   string
+		pc     uint16
+		offset uint8
+		want   uint16
+		cycles uint8
+	}{
+		{"forward", 0x1000, 0x10, 0x1012, 3},
+		{"backward", 0x1010, 0xFC, 0x100E, 3},
+		{"page crossing", 0x10F0, 0x20, 0x1112, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCMOSTestCPU()
+			c.PC = tt.pc
+			c.Memory[tt.pc] = BRA
+			c.Memory[tt.pc+1] = tt.offset
+			c.P = 0xFF // Taken whatever the flags
+
+			assert.Equal(t, tt.cycles, c.Step())
+			assert.Equal(t, tt.want, c.PC)
+			assert.Equal(t, uint8(0xFF), c.P)
+		})
+	}
+}
+
+func TestSTZ(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		addr    uint16
+		cycles  uint8
+	}{
+		{"zero page", []uint8{STZ_ZP, 0x42}, 0x0042, 3},
+		{"zero page,X wraps", []uint8{STZ_ZPX, 0xF8}, 0x0003, 4},
+		{"absolute", []uint8{STZ_ABS, 0x34, 0x12}, 0x1234, 4},
+		{"absolute,X", []uint8{STZ_ABX, 0xF8, 0x12}, 0x1303, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCMOSTestCPU(tt.program...)
+			c.A = 0x55
+			c.X = 0x0B
+			c.Memory[tt.addr] = 0xAA
+
+			assert.Equal(t, tt.cycles, c.Step())
+			assert.Equal(t, uint8(0x00), c.Memory[tt.addr])
+			assert.Equal(t, uint8(0x55), c.A, "A is not stored")
+		})
+	}
+}
+
+func TestJMPIndirectPageBoundary(t *testing.T) {
+	tests := []struct {
+		name   string
+		cpu    Variant
+		want   uint16
+		cycles uint8
+	}{
+		{"NMOS wraps within the page", NMOS6502, 0x4080, 5},
+		{"65C02 reads the next page", CMOS65C02, 0x5080, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCMOSTestCPU(JMP_IND, 0xFF, 0x30)
+			c.Variant = tt.cpu
+			c.Memory[0x30FF] = 0x80
+			c.Memory[0x3000] = 0x40
+			c.Memory[0x3100] = 0x50
+
+			assert.Equal(t, tt.cycles, c.Step())
+			assert.Equal(t, tt.want, c.PC)
+		})
+	}
+}
+
+func TestCMOSInstructions(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		setup   func(*CPUAndMemory)
+		verify  func(*testing.T, *CPUAndMemory)
+		cycles  uint8
+	}{
+		{
+			name:    "PHX then PLY",
+			program: []uint8{PHX, PLY},
+			setup:   func(c *CPUAndMemory) { c.X = 0x80 },
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				c.Step()
+				assert.Equal(t, uint8(0x80), c.Y)
+				assert.Equal(t, FlagN, c.P&FlagN)
+				assert.Equal(t, uint8(0xFF), c.SP)
+			},
+			cycles: 3,
+		},
+		{
+			name:    "TSB",
+			program: []uint8{TSB_ZP, 0x10},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x0F
+				c.Memory[0x10] = 0xF0
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0xFF), c.Memory[0x10])
+				assert.Equal(t, FlagZ, c.P&FlagZ, "no bits in common")
+			},
+			cycles: 5,
+		},
+		{
+			name:    "TRB",
+			program: []uint8{TRB_ABS, 0x00, 0x20},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x0F
+				c.Memory[0x2000] = 0xFF
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0xF0), c.Memory[0x2000])
+				assert.Equal(t, uint8(0), c.P&FlagZ)
+			},
+			cycles: 6,
+		},
+		{
+			name:    "LDA (zp)",
+			program: []uint8{LDA_IZP, 0xFF},
+			setup: func(c *CPUAndMemory) {
+				c.Memory[0xFF] = 0x34
+				c.Memory[0x00] = 0x12 // Pointer wraps within the zero page
+				c.Memory[0x1234] = 0x99
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x99), c.A)
+				assert.Equal(t, FlagN, c.P&FlagN)
+			},
+			cycles: 5,
+		},
+		{
+			name:    "STA (zp)",
+			program: []uint8{STA_IZP, 0x20},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x42
+				c.Memory[0x20] = 0x00
+				c.Memory[0x21] = 0x30
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x42), c.Memory[0x3000])
+			},
+			cycles: 5,
+		},
+		{
+			name:    "BIT immediate only sets Z",
+			program: []uint8{BIT_IMM, 0xC0},
+			setup:   func(c *CPUAndMemory) { c.A = 0x01 },
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, FlagZ, c.P&(FlagN|FlagV|FlagZ))
+			},
+			cycles: 2,
+		},
+		{
+			name:    "INC A",
+			program: []uint8{INC_ACC},
+			setup:   func(c *CPUAndMemory) { c.A = 0xFF },
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x00), c.A)
+				assert.Equal(t, FlagZ, c.P&FlagZ)
+			},
+			cycles: 2,
+		},
+		{
+			name:    "JMP (abs,X)",
+			program: []uint8{JMP_IAX, 0x00, 0x20},
+			setup: func(c *CPUAndMemory) {
+				c.X = 0x04
+				c.Memory[0x2004] = 0x78
+				c.Memory[0x2005] = 0x56
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint16(0x5678), c.PC)
+			},
+			cycles: 6,
+		},
+		{
+			name:    "decimal ADC sets N and Z from the result",
+			program: []uint8{ADC_IMM, 0x01},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x99
+				c.P = FlagD
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x00), c.A)
+				assert.Equal(t, FlagZ|FlagC, c.P&(FlagN|FlagZ|FlagC))
+			},
+			cycles: 3,
+		},
+		{
+			name:    "decimal SBC sets N and Z from the result",
+			program: []uint8{SBC_IMM, 0x01},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x00
+				c.P = FlagD | FlagC
+			},
+			verify: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x99), c.A)
+				assert.Equal(t, FlagN, c.P&(FlagN|FlagZ|FlagC))
+			},
+			cycles: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCMOSTestCPU(tt.program...)
+			tt.setup(c)
+
+			assert.Equal(t, tt.cycles, c.Step())
+			tt.verify(t, c)
+		})
+	}
+}
+
+func TestCMOSOpcodesRequireCMOS(t *testing.T) {
+	c := NewCPUAndMemory()
+	c.Memory[0x0000] = STZ_ABS
+	assert.Panics(t, func() { c.Step() })
+}
@@ -262,3 +262,78 @@ func TestCMPAddressingModes(t *testing.T) {
 		}
 	}
 }
+
+// TestCompareBoundaries checks CMP/CPX/CPY against the hardware truth table
+// at the signed and unsigned boundaries. Comparisons are unsigned: C means
+// reg >= value, Z means equal and N is bit 7 of reg-value. V is never touched.
+func TestCompareBoundaries(t *testing.T) {
+	boundaries := []struct {
+		reg       uint8
+		value     uint8
+		expectedC bool
+		expectedZ bool
+		expectedN bool
+	}{
+		{0x00, 0x80, false, false, true},  // $00-$80 = $80
+		{0x80, 0x00, true, false, true},   // $80-$00 = $80
+		{0x7F, 0x80, false, false, true},  // $7F-$80 = $FF
+		{0x80, 0x7F, true, false, false},  // $80-$7F = $01
+		{0xFF, 0x00, true, false, true},   // $FF-$00 = $FF
+		{0x00, 0xFF, false, false, false}, // $00-$FF = $01
+		{0x80, 0x80, true, true, false},
+		{0x00, 0x00, true, true, false},
+		{0xFF, 0xFF, true, true, false},
+		{0x7F, 0xFF, false, false, true}, // $7F-$FF = $80
+		{0xFF, 0x7F, true, false, true},  // $FF-$7F = $80
+	}
+
+	ops := []struct {
+		name   string
+		opcode uint8
+		setReg func(*CPUAndMemory, uint8)
+	}{
+		{"CMP", CMP_IMM, func(c *CPUAndMemory, v uint8) { c.A = v }},
+		{"CPX", CPX_IMM, func(c *CPUAndMemory, v uint8) { c.X = v }},
+		{"CPY", CPY_IMM, func(c *CPUAndMemory, v uint8) { c.Y = v }},
+	}
+
+	for _, op := range ops {
+		for _, b := range boundaries {
+			t.Run(fmt.Sprintf("%s_%02X_%02X", op.name, b.reg, b.value), func(t *testing.T) {
+				for _, v := range []uint8{0, FlagV} {
+					c := NewCPUAndMemory()
+					op.setReg(c, b.reg)
+					c.P = v
+					c.Memory[0] = b.value
+
+					c.execute(op.opcode)
+
+					assert.Equal(t, b.expectedC, c.P&FlagC != 0, "Carry flag mismatch")
+					assert.Equal(t, b.expectedZ, c.P&FlagZ != 0, "Zero flag mismatch")
+					assert.Equal(t, b.expectedN, c.P&FlagN != 0, "Negative flag mismatch")
+					assert.Equal(t, v, c.P&FlagV, "Overflow flag must be preserved")
+				}
+			})
+		}
+	}
+
+	// Sweep every register/operand pair for CMP
+	c := NewCPUAndMemory()
+	for reg := 0; reg < 256; reg++ {
+		for value := 0; value < 256; value++ {
+			c.A = uint8(reg)
+			c.P = 0
+			c.PC = 0
+			c.Memory[0] = uint8(value)
+			c.execute(CMP_IMM)
+
+			diff := uint8(reg - value)
+			if (c.P&FlagC != 0) != (reg >= value) ||
+				(c.P&FlagZ != 0) != (reg == value) ||
+				(c.P&FlagN != 0) != (diff&0x80 != 0) ||
+				c.P&FlagV != 0 {
+				t.Fatalf("CMP $%02X vs $%02X: unexpected flags $%02X", reg, value, c.P)
+			}
+		}
+	}
+}
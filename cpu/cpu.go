@@ -1,6 +1,9 @@
 package cpu
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
 // The naming convention uses the instruction name followed by the addressing mode:
 //
@@ -205,6 +208,10 @@ const (
 	BRK = 0x00
 	NOP = 0xEA
 	RTI = 0x40
+
+	// 65C02 power management, only decoded when CPU.Variant is CMOS65C02
+	WAI = 0xCB
+	STP = 0xDB
 )
 
 // CPU represents the 6502 processor
@@ -217,8 +224,44 @@ type CPU struct {
 	SP uint8  // Stack Pointer
 	P  uint8  // Status Register (Flags)
 
+	Cycles uint64 // Cycles consumed by Step since creation
+
 	// Memory interface instead of direct array
 	Bus MemoryBus
+
+	// Variant selects the NMOS 6502 (the default) or the 65C02. The 65C02
+	// adds the CMOS opcodes (BRA, PHX/PHY/PLX/PLY, STZ, TRB/TSB, (zp)
+	// addressing, WAI, STP, ...), fixes the JMP ($xxFF) page bug and sets
+	// valid N and Z flags in decimal mode, at the cost of an extra cycle for
+	// ADC and SBC
+	Variant Variant
+
+	// EnableIllegal executes the undocumented opcodes in IllegalOpcodes
+	// instead of panicking. Jammed is set once a KIL opcode halts the CPU.
+	EnableIllegal bool
+	Jammed        bool
+
+	// OnCycle, if set, is called once per cycle consumed by Step: before
+	// each bus access, then for any remaining internal cycles once the
+	// instruction completes. Peripherals advanced from it see reads and
+	// writes at the cycle they happen.
+	OnCycle   func()
+	stepping  bool
+	busCycles uint8 // Cycles already reported to OnCycle in this Step
+
+	irqLine    bool  // Level-triggered IRQ input
+	iPrev      uint8 // I flag as it was before the last CLI/SEI/PLP
+	iDelay     bool  // iPrev is still what the interrupt poll sees
+	irqPending bool  // One-shot IRQ requested by IRQ()
+	nmiPending bool  // NMI requested by NMI(); taken regardless of I
+	waiting    bool  // WAI executed; idle until IRQ is asserted
+	stopped    bool  // STP executed; idle until Reset
+	stall      int   // Dead cycles from Stall not yet consumed
+
+	trace io.Writer // Receives a line per instruction; see SetTraceWriter
+
+	// Profiler, if set, accumulates per-PC execution counts and cycles
+	Profiler *Profiler
 }
 
 // Status flag bits
@@ -237,6 +280,22 @@ type MemoryBus interface {
 	Write(address uint16, value uint8)
 }
 
+// Peeker is implemented by buses that can return a byte without the side
+// effects of Read, such as clearing a device's status latch or firing a
+// watchpoint. Tools that inspect memory between bus cycles use it.
+type Peeker interface {
+	Peek(address uint16) uint8
+}
+
+// Peek reads address from bus through Peeker when it is implemented, and
+// through Read otherwise
+func Peek(bus MemoryBus, address uint16) uint8 {
+	if p, ok := bus.(Peeker); ok {
+		return p.Peek(address)
+	}
+	return bus.Read(address)
+}
+
 // NewCPU creates a new 6502 CPU instance
 func NewCPU(b MemoryBus) *CPU {
 	return &CPU{
@@ -248,14 +307,24 @@ func NewCPU(b MemoryBus) *CPU {
 
 // Read reads a byte from memory
 func (c *CPU) Read(address uint16) uint8 {
+	c.tick()
 	return c.Bus.Read(address)
 }
 
 // Write writes a byte to memory
 func (c *CPU) Write(address uint16, value uint8) {
+	c.tick()
 	c.Bus.Write(address, value)
 }
 
+// tick reports one bus cycle to OnCycle while an instruction is executing
+func (c *CPU) tick() {
+	if c.OnCycle != nil && c.stepping {
+		c.busCycles++
+		c.OnCycle()
+	}
+}
+
 // Reset resets the CPU to its initial state
 func (c *CPU) Reset() {
 	// Read reset vector at 0xFFFC-0xFFFD
@@ -269,16 +338,196 @@ func (c *CPU) Reset() {
 	c.A = 0
 	c.X = 0
 	c.Y = 0
+	c.waiting = false
+	c.stopped = false
+	c.irqPending = false
+	c.nmiPending = false
+	c.Jammed = false
+	c.stall = 0
+}
+
+// Halted reports whether WAI, STP or a KIL opcode has idled the CPU
+func (c *CPU) Halted() bool {
+	return c.waiting || c.stopped || c.Jammed
+}
+
+// SetIRQ drives the level-triggered IRQ line. While asserted and the I flag
+// is clear, the CPU enters the IRQ handler at the next instruction boundary.
+func (c *CPU) SetIRQ(asserted bool) {
+	c.irqLine = asserted
 }
 
-// Step executes one instruction and returns number of cycles used
+// IRQ requests a single maskable interrupt. It is serviced at the next
+// instruction boundary if the I flag is clear there and dropped otherwise;
+// use SetIRQ for a device that holds the line until acknowledged.
+func (c *CPU) IRQ() {
+	c.irqPending = true
+}
+
+// NMI requests a non-maskable interrupt, serviced at the next instruction
+// boundary through $FFFA/$FFFB whatever the I flag.
+func (c *CPU) NMI() {
+	c.nmiPending = true
+}
+
+// InterruptPending reports whether an interrupt will be considered at the
+// next instruction boundary
+func (c *CPU) InterruptPending() bool {
+	return c.irqLine || c.irqPending || c.nmiPending
+}
+
+// maxStepStall caps the stalled cycles one Step consumes, leaving room in
+// its uint8 result for the longest instruction
+const maxStepStall = 0xFF - 8
+
+// Stall holds the CPU off the bus for cycles, as when a video chip steals
+// it. The next Step consumes the dead cycles before running its
+// instruction, counting them toward Cycles and reporting them to OnCycle.
+// A stall longer than maxStepStall cycles is spread over several Steps.
+func (c *CPU) Stall(cycles int) {
+	if cycles > 0 {
+		c.stall += cycles
+	}
+}
+
+// Step executes one instruction and returns number of cycles used, including
+// any stall consumed before it. A Step that enters an interrupt handler, or
+// finds the CPU idle after WAI, STP or a KIL opcode, runs no instruction.
 func (c *CPU) Step() uint8 {
+	stalled := c.consumeStall()
+	executing := c.executing()
+	if c.trace != nil && executing {
+		c.writeTrace()
+	}
+	pc := c.PC
+	c.stepping = true
+	c.busCycles = 0
+	cycles := c.step()
+	c.stepping = false
+	c.Cycles += uint64(cycles)
+	if c.OnCycle != nil {
+		for ; c.busCycles < cycles; c.busCycles++ {
+			c.OnCycle()
+		}
+	}
+	if c.Profiler != nil && executing {
+		c.Profiler.record(pc, cycles)
+	}
+	return stalled + cycles
+}
+
+// consumeStall spends up to maxStepStall pending stall cycles, returning
+// how many
+func (c *CPU) consumeStall() uint8 {
+	cycles := uint8(min(c.stall, maxStepStall))
+	c.stall -= int(cycles)
+	c.Cycles += uint64(cycles)
+	if c.OnCycle != nil {
+		for i := uint8(0); i < cycles; i++ {
+			c.OnCycle()
+		}
+	}
+	return cycles
+}
+
+// Run steps until stop returns true, the next instruction is BRK, the CPU
+// halts, or at least maxCycles have been consumed (0 means no budget). stop
+// is checked before each instruction, so a breakpoint on the current PC
+// fires without executing it. The BRK check goes through Peek, so buses
+// implementing Peeker see no extra access. A WAI with no interrupt pending
+// ends the run too; raise one and call Run again to resume. Returns the
+// cycles consumed.
+func (c *CPU) Run(maxCycles uint64, stop func(*CPU) bool) uint64 {
+	var consumed uint64
+	for maxCycles == 0 || consumed < maxCycles {
+		if stop != nil && stop(c) {
+			break
+		}
+		if c.Jammed || c.stopped || (c.waiting && !c.InterruptPending()) || Peek(c.Bus, c.PC) == BRK {
+			break
+		}
+		consumed += uint64(c.Step())
+	}
+	return consumed
+}
+
+// executing reports whether the next step will run an instruction, rather
+// than idle or enter an interrupt handler
+func (c *CPU) executing() bool {
+	switch {
+	case c.stopped || c.Jammed:
+		return false
+	case c.waiting && !c.InterruptPending():
+		return false
+	}
+	return !c.nmiPending && !c.irqTaken()
+}
+
+// irqTaken reports whether the IRQ poll at this instruction boundary enters
+// the handler. The 6502 polls IRQ during the last cycle of each instruction,
+// but CLI, SEI and PLP only change I after that poll. So an IRQ held across
+// CLI is taken one instruction late, and one arriving just before SEI still
+// wins.
+func (c *CPU) irqTaken() bool {
+	iFlag := c.P & FlagI
+	if c.iDelay {
+		iFlag = c.iPrev
+	}
+	return (c.irqLine || c.irqPending) && iFlag == 0
+}
+
+func (c *CPU) step() uint8 {
+	// STP and KIL idle until Reset. WAI idles until an interrupt is requested, then
+	// resumes with the handler or, with I set, the instruction after WAI.
+	if c.stopped || c.Jammed {
+		return 1
+	}
+	if c.waiting {
+		if !c.InterruptPending() {
+			return 1
+		}
+		c.waiting = false
+	}
+
+	if c.nmiPending {
+		c.nmiPending = false
+		return c.interrupt(0xFFFA)
+	}
+
+	irq := c.irqTaken()
+	c.iDelay = false
+	c.irqPending = false
+	if irq {
+		return c.interrupt(0xFFFE)
+	}
+
 	// Fetch
 	opcode := c.Read(c.PC)
 	c.PC++
 
 	// Decode and Execute
-	return c.execute(opcode)
+	before := c.P & FlagI
+	cycles := c.execute(opcode)
+	if c.cmos() && c.P&FlagD != 0 && decimalPenalty(opcode) {
+		cycles++
+	}
+	switch opcode {
+	case CLI, SEI, PLP:
+		if c.P&FlagI != before {
+			c.iPrev = before
+			c.iDelay = true
+		}
+	}
+	return cycles
+}
+
+// interrupt pushes PC and status (with B clear) and jumps through vector
+func (c *CPU) interrupt(vector uint16) uint8 {
+	c.push16(c.PC)
+	c.push(c.P &^ FlagB)
+	c.P |= FlagI
+	c.PC = uint16(c.Read(vector)) | uint16(c.Read(vector+1))<<8
+	return 7
 }
 
 // execute processes a single opcode
@@ -359,19 +608,7 @@ func (c *CPU) execute(opcode uint8) uint8 {
 		return 4
 
 	case LDX_ABY: // Note: LDX uses Y register for indexing!
-		value, pageCrossed := func() (uint8, bool) {
-			lowByte := uint16(c.Read(c.PC))
-			c.PC++
-			highByte := uint16(c.Read(c.PC))
-			c.PC++
-			addr := (highByte << 8) | lowByte
-			finalAddr := addr + uint16(c.Y)
-
-			// Check if page boundary was crossed
-			pageCrossed := (addr & 0xFF00) != (finalAddr & 0xFF00)
-
-			return c.Read(uint16(finalAddr)), pageCrossed
-		}()
+		value, pageCrossed := c.readAbsoluteY()
 
 		c.X = value
 		c.updateZN(c.X)
@@ -403,19 +640,7 @@ func (c *CPU) execute(opcode uint8) uint8 {
 		return 4
 
 	case LDY_ABX: // Note: LDY uses X register for indexing!
-		value, pageCrossed := func() (uint8, bool) {
-			lowByte := uint16(c.Read(c.PC))
-			c.PC++
-			highByte := uint16(c.Read(c.PC))
-			c.PC++
-			addr := (highByte << 8) | lowByte
-			finalAddr := addr + uint16(c.X)
-
-			// Check if page boundary was crossed
-			pageCrossed := (addr & 0xFF00) != (finalAddr & 0xFF00)
-
-			return c.Read(uint16(finalAddr)), pageCrossed
-		}()
+		value, pageCrossed := c.readAbsoluteX()
 
 		c.Y = value
 		c.updateZN(c.Y)
@@ -1056,15 +1281,13 @@ func (c *CPU) execute(opcode uint8) uint8 {
 		return 3
 
 	case JMP_IND:
-		addr := c.readAbsoluteAddress()
-		// Handle 6502 indirect jump bug at page boundary
-		if addr&0xFF == 0xFF {
-			low := uint16(c.Read(uint16(addr)))
-			high := uint16(c.Read(addr & 0xFF00))
-			c.PC = (high << 8) | low
-		} else {
-			c.PC = uint16(c.Read(uint16(addr))) | uint16(c.Read(addr+1))<<8
+		if c.cmos() {
+			// The 65C02 carries into the high byte, spending a cycle on it
+			addr := c.readAbsoluteAddress()
+			c.PC = uint16(c.Read(addr)) | uint16(c.Read(addr+1))<<8
+			return 6
 		}
+		c.PC = c.readJumpVector(c.readAbsoluteAddress())
 		return 5
 
 	case JSR_ABS:
@@ -1129,15 +1352,36 @@ func (c *CPU) execute(opcode uint8) uint8 {
 	case NOP:
 		return 2
 
+	case WAI:
+		if !c.cmos() {
+			break
+		}
+		c.waiting = true
+		return 3
+	case STP:
+		if !c.cmos() {
+			break
+		}
+		c.stopped = true
+		return 3
+
 	case RTI:
 		c.P = c.pull() & ^FlagB // Pull status, clear B flag
 		c.PC = c.pull16()       // Pull return address
 		return 6
 
-	default:
-		panic(fmt.Sprintf("Unknown opcode: 0x%02X", opcode))
 	}
-	return 0
+	if c.cmos() {
+		if cycles, ok := c.executeCMOS(opcode); ok {
+			return cycles
+		}
+	}
+	if c.EnableIllegal {
+		if cycles, ok := c.executeIllegal(opcode); ok {
+			return cycles
+		}
+	}
+	panic(fmt.Sprintf("Unknown opcode: 0x%02X", opcode))
 }
 
 // branch performs a relative branch if condition is true
@@ -1147,11 +1391,13 @@ func (c *CPU) branch(condition bool) uint8 {
 		return 2 // Branch not taken
 	}
 
-	oldPC := c.PC
+	// readImmediate has left PC on the instruction after the branch, which
+	// is what the offset is relative to and what the page check compares
+	nextPC := c.PC
 	c.PC = uint16(int32(c.PC) + int32(offset))
 
-	// Extra cycle if branch crosses page boundary
-	if (oldPC & 0xFF00) != (c.PC & 0xFF00) {
+	// Extra cycle if the target is on a different page than the next instruction
+	if (nextPC & 0xFF00) != (c.PC & 0xFF00) {
 		return 4 // Page boundary crossed
 	}
 	return 3 // Branch taken, no page boundary cross
@@ -1288,58 +1534,55 @@ func (c *CPU) cmp(value uint8) {
 
 // Helper function for SBC operation
 func (c *CPU) sbc(value uint8) {
-	// SBC operation is equivalent to ADC of the two's complement
-	c.adc(^value) // ^ operator performs NOT operation
-}
+	borrow := 1 - int(c.P&FlagC)
+	result := int(c.A) - int(value) - borrow
 
-// Helper function for SBC operation
-//func (c *CPU) sbc(value uint8) {
-//	carry := uint8(1)
-//	if c.P&FlagC != 0 {
-//		carry = 0
-//	}
-//
-//	// Save original accumulator value for overflow check
-//	orig := c.A
-//	result := uint16(c.A) - uint16(value) - uint16(carry)
-//
-//	// Set carry flag (inverted borrow)
-//	if result < 0x100 {
-//		c.P |= FlagC
-//	} else {
-//		c.P &^= FlagC
-//	}
-//
-//	// Set overflow flag
-//	// Overflow occurs when the sign of the result is incorrect
-//	// This happens when:
-//	// 1. Subtracting a positive from a negative gives a positive
-//	// 2. Subtracting a negative from a positive gives a negative
-//	if ((orig^value)&0x80 != 0) && ((orig^uint8(result))&0x80 != 0) {
-//		c.P |= FlagV
-//	} else {
-//		c.P &^= FlagV
-//	}
-//
-//	// Store result and update N and Z flags
-//	c.A = uint8(result)
-//	c.updateZN(c.A)
-//}
+	// Carry is the inverted borrow
+	if result >= 0 {
+		c.P |= FlagC
+	} else {
+		c.P &^= FlagC
+	}
 
-func (c *CPU) adc(value uint8) {
-	// Convert to uint16 to handle carry bit
-	sum := uint16(c.A) + uint16(value) + uint16(c.P&FlagC)
+	// Overflow occurs when the operands' signs differ and the result's sign
+	// differs from A's
+	if (c.A^value)&0x80 != 0 && (c.A^uint8(result))&0x80 != 0 {
+		c.P |= FlagV
+	} else {
+		c.P &^= FlagV
+	}
+
+	// On the NMOS 6502, N and Z follow the binary result even in decimal
+	// mode
+	c.updateZN(uint8(result))
 
-	// Handle decimal mode if D flag is set
 	if c.P&FlagD != 0 {
-		// Convert to BCD
-		if ((c.A & 0xF) + (value & 0xF) + (c.P & FlagC)) > 9 {
-			sum += 0x6
+		// Subtract the BCD digits separately, adjusting each on borrow
+		low := int(c.A&0x0F) - int(value&0x0F) - borrow
+		high := int(c.A&0xF0) - int(value&0xF0)
+		if low < 0 {
+			low -= 6
+			high -= 0x10
+		}
+		if high < 0 {
+			high -= 0x60
 		}
-		if sum > 0x99 {
-			sum += 0x60
+		result = high + low&0x0F
+		if c.cmos() {
+			c.updateZN(uint8(result))
 		}
 	}
+	c.A = uint8(result)
+}
+
+func (c *CPU) adc(value uint8) {
+	if c.P&FlagD != 0 {
+		c.adcDecimal(value)
+		return
+	}
+
+	// Convert to uint16 to handle carry bit
+	sum := uint16(c.A) + uint16(value) + uint16(c.P&FlagC)
 
 	// Set carry flag
 	if sum > 0xFF {
@@ -1362,6 +1605,43 @@ func (c *CPU) adc(value uint8) {
 	c.updateZN(c.A)
 }
 
+// adcDecimal adds in BCD with the NMOS flag quirks described in Bruce
+// Clark's decimal mode tutorial. Z follows the binary sum, N and V follow
+// the sum with only the low digit adjusted, and C the fully adjusted
+// result. Invalid BCD digits produce the same results as the hardware.
+func (c *CPU) adcDecimal(value uint8) {
+	carry := uint16(c.P & FlagC)
+	binary := uint8(uint16(c.A) + uint16(value) + carry)
+
+	low := uint16(c.A&0x0F) + uint16(value&0x0F) + carry
+	if low >= 0x0A {
+		low = ((low + 0x06) & 0x0F) + 0x10
+	}
+	sum := uint16(c.A&0xF0) + uint16(value&0xF0) + low
+	signed := int(int8(c.A&0xF0)) + int(int8(value&0xF0)) + int(low)
+
+	c.P &^= FlagN | FlagV | FlagZ | FlagC
+	if sum&0x80 != 0 {
+		c.P |= FlagN
+	}
+	if signed < -128 || signed > 127 {
+		c.P |= FlagV
+	}
+	if binary == 0 {
+		c.P |= FlagZ
+	}
+	if sum >= 0xA0 {
+		sum += 0x60
+	}
+	if sum >= 0x100 {
+		c.P |= FlagC
+	}
+	c.A = uint8(sum)
+	if c.cmos() {
+		c.updateZN(c.A)
+	}
+}
+
 func (c *CPU) readImmediate() uint8 {
 	value := c.Read(c.PC)
 	c.PC++
@@ -1395,12 +1675,7 @@ func (c *CPU) readAbsoluteX() (uint8, bool) {
 	highByte := uint16(c.Read(c.PC))
 	c.PC++
 	addr := (highByte << 8) | lowByte
-	finalAddr := addr + uint16(c.X)
-
-	// Return true if page boundary crossed (extra cycle)
-	pageCrossed := (addr & 0xFF00) != (finalAddr & 0xFF00)
-
-	return c.Read(uint16(finalAddr)), pageCrossed
+	return c.readIndexed(addr, c.X)
 }
 
 func (c *CPU) readAbsoluteY() (uint8, bool) {
@@ -1409,11 +1684,7 @@ func (c *CPU) readAbsoluteY() (uint8, bool) {
 	highByte := uint16(c.Read(c.PC))
 	c.PC++
 	addr := (highByte << 8) | lowByte
-	finalAddr := addr + uint16(c.Y)
-
-	pageCrossed := (addr & 0xFF00) != (finalAddr & 0xFF00)
-
-	return c.Read(uint16(finalAddr)), pageCrossed
+	return c.readIndexed(addr, c.Y)
 }
 
 func (c *CPU) readIndirectX() uint8 {
@@ -1440,11 +1711,27 @@ func (c *CPU) readIndirectY() (uint8, bool) {
 	highByte := uint16(c.Read(uint16(zeroPageAddr+1) & 0xFF))
 
 	baseAddr := (highByte << 8) | lowByte
-	finalAddr := baseAddr + uint16(c.Y)
+	return c.readIndexed(baseAddr, c.Y)
+}
 
-	pageCrossed := (baseAddr & 0xFF00) != (finalAddr & 0xFF00)
+// readIndexed reads from base+index and reports whether a page boundary was
+// crossed. Like the NMOS 6502, a page crossing first performs a dummy read at
+// the un-fixed address (the original high byte with the carried low byte),
+// which matters for I/O registers with read side effects.
+func (c *CPU) readIndexed(base uint16, index uint8) (uint8, bool) {
+	finalAddr, pageCrossed := c.indexedAddress(base, index)
+	return c.Read(finalAddr), pageCrossed
+}
 
-	return c.Read(uint16(finalAddr)), pageCrossed
+// indexedAddress returns base+index and whether a page boundary was crossed,
+// making the page-crossing dummy read that readIndexed describes
+func (c *CPU) indexedAddress(base uint16, index uint8) (uint16, bool) {
+	finalAddr := base + uint16(index)
+	pageCrossed := (base & 0xFF00) != (finalAddr & 0xFF00)
+	if pageCrossed {
+		c.Read((base & 0xFF00) | (finalAddr & 0x00FF))
+	}
+	return finalAddr, pageCrossed
 }
 
 func (c *CPU) readAbsoluteAddress() uint16 {
@@ -1462,6 +1749,16 @@ func (c *CPU) readIndirectAddress(zeroPageAddr uint8) uint16 {
 	return (highByte << 8) | lowByte
 }
 
+// readJumpVector reads the JMP ($xxxx) target. The 6502 only increments the
+// low byte of the vector address, so a vector at $30FF takes its high byte
+// from $3000, not $3100. This differs from readIndirectAddress, whose
+// pointers wrap within the zero page.
+func (c *CPU) readJumpVector(addr uint16) uint16 {
+	low := uint16(c.Read(addr))
+	high := uint16(c.Read(addr&0xFF00 | (addr+1)&0x00FF))
+	return high<<8 | low
+}
+
 // Add helper functions for stack operations
 func (c *CPU) push(value uint8) {
 	c.Write(0x0100|uint16(c.SP), value)
@@ -1514,6 +1811,9 @@ func (c *CPUAndMemory) Read(address uint16) uint8 {
 func (c *CPUAndMemory) Write(address uint16, value uint8) {
 	c.Memory[address] = value
 }
+func (c *CPUAndMemory) Peek(address uint16) uint8 {
+	return c.Memory[address]
+}
 
 func NewCPUAndMemory() *CPUAndMemory {
 	c := &CPUAndMemory{
@@ -0,0 +1,127 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOnCycleCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		setup   func(*CPUAndMemory)
+		cycles  uint8
+	}{
+		{"LDA immediate", []uint8{LDA_IMM, 0x01}, func(c *CPUAndMemory) {}, 2},
+		{"LDA absolute,X page cross", []uint8{LDA_ABX, 0xFF, 0x20}, func(c *CPUAndMemory) { c.X = 1 }, 5},
+		{"INC absolute,X", []uint8{INC_ABX, 0x00, 0x20}, func(c *CPUAndMemory) { c.X = 1 }, 7},
+		{"JSR", []uint8{JSR_ABS, 0x00, 0x20}, func(c *CPUAndMemory) {}, 6},
+		{"IRQ entry", []uint8{NOP}, func(c *CPUAndMemory) { c.P = 0x20; c.IRQ() }, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCPUAndMemory()
+			copy(c.Memory[0x1000:], tt.program)
+			c.PC = 0x1000
+			tt.setup(c)
+			ticks := 0
+			c.OnCycle = func() { ticks++ }
+
+			cycles := c.Step()
+
+			assert.Equal(t, tt.cycles, cycles)
+			assert.Equal(t, int(cycles), ticks)
+		})
+	}
+}
+
+// TestOnCycleEveryOpcode checks that no opcode makes more bus accesses than
+// the cycles it reports, which would over-count OnCycle.
+func TestOnCycleEveryOpcode(t *testing.T) {
+	for opcode := 0; opcode < 256; opcode++ {
+		c := NewCPUAndMemory()
+		c.EnableIllegal = true
+		c.Memory[0x1000] = uint8(opcode)
+		c.Memory[0x1001] = 0xFF
+		c.Memory[0x1002] = 0x20
+		c.PC = 0x1000
+		c.X, c.Y = 1, 1
+		ticks := 0
+		c.OnCycle = func() { ticks++ }
+
+		var cycles uint8
+		panicked := func() (panicked bool) {
+			defer func() { panicked = recover() != nil }()
+			cycles = c.Step()
+			return false
+		}()
+		if panicked {
+			continue
+		}
+		assert.Equal(t, int(cycles), ticks, "opcode $%02X", opcode)
+	}
+}
+
+func TestOnCycleInterleavesReads(t *testing.T) {
+	c := NewCPUAndMemory()
+	copy(c.Memory[0x1000:], []uint8{LDA_ABS, 0x12, 0xD0})
+	c.PC = 0x1000
+	// A free-running counter standing in for a raster register
+	c.OnCycle = func() { c.Memory[0xD012]++ }
+
+	c.Step()
+
+	// Advanced for the opcode, both operand bytes and the read itself
+	assert.Equal(t, uint8(4), c.A)
+}
+
+func TestStall(t *testing.T) {
+	c := NewCPUAndMemory()
+	copy(c.Memory[0x1000:], []uint8{LDA_IMM, 0x01, NOP, NOP, NOP})
+	c.PC = 0x1000
+	ticks := 0
+	var stalledTicks int
+	c.OnCycle = func() { ticks++ }
+
+	// A bad line's worth of dead cycles comes before the next instruction,
+	// which still runs in the same Step
+	c.Stall(43)
+	c.Bus = &tickCountingBus{MemoryBus: c.Bus, first: &stalledTicks, ticks: &ticks}
+	assert.Equal(t, uint8(45), c.Step())
+	assert.Equal(t, 43+1, stalledTicks, "the stall is reported before the opcode fetch")
+	assert.Equal(t, uint16(0x1002), c.PC)
+	assert.Equal(t, uint8(0x01), c.A)
+	assert.Equal(t, uint64(45), c.Cycles)
+	assert.Equal(t, 45, ticks)
+
+	// Long stalls are spread over several Steps, each running an instruction
+	c.Stall(300)
+	c.Stall(-5)
+	assert.Equal(t, uint8(maxStepStall+2), c.Step())
+	assert.Equal(t, uint16(0x1003), c.PC)
+	assert.Equal(t, uint8(300-maxStepStall+2), c.Step())
+	assert.Equal(t, uint16(0x1004), c.PC)
+	assert.Equal(t, uint64(349), c.Cycles)
+
+	// Run counts stalled cycles against its budget
+	c.Stall(10)
+	assert.Equal(t, uint64(12), c.Run(11, nil))
+	assert.Equal(t, uint16(0x1005), c.PC)
+}
+
+// tickCountingBus records how many OnCycle ticks came before its first read
+type tickCountingBus struct {
+	MemoryBus
+	first *int
+	ticks *int
+	read  bool
+}
+
+func (b *tickCountingBus) Read(address uint16) uint8 {
+	if !b.read {
+		b.read = true
+		*b.first = *b.ticks
+	}
+	return b.MemoryBus.Read(address)
+}
@@ -0,0 +1,58 @@
+package cpu
+
+import (
+	"os"
+	"strconv"
+	"testing"
+)
+
+// Klaus Dormann's 6502_functional_test.bin is a full 64K image that starts
+// at $0400 and ends in a JMP * trap: at the success address when every test
+// passes, or at the failing check otherwise. It is not shipped with the
+// repo; point FUNCTIONAL_TEST_BIN at a copy to run it, and set
+// FUNCTIONAL_TEST_SUCCESS (hex) if it was assembled with a different layout.
+const (
+	functionalTestStart   = 0x0400
+	functionalTestSuccess = 0x3469 // Documented trap for the default build
+	functionalTestLimit   = 200_000_000
+)
+
+func TestFunctionalSuite(t *testing.T) {
+	path := os.Getenv("FUNCTIONAL_TEST_BIN")
+	if path == "" {
+		path = "../6502_functional_test.bin"
+	}
+	image, err := os.ReadFile(path)
+	if err != nil {
+		t.Skipf("functional test binary not available: %v", err)
+	}
+	if len(image) != 0x10000 {
+		t.Fatalf("%s is %d bytes, expected a 64K image", path, len(image))
+	}
+
+	success := uint16(functionalTestSuccess)
+	if s := os.Getenv("FUNCTIONAL_TEST_SUCCESS"); s != "" {
+		value, err := strconv.ParseUint(s, 16, 16)
+		if err != nil {
+			t.Fatalf("FUNCTIONAL_TEST_SUCCESS: %v", err)
+		}
+		success = uint16(value)
+	}
+
+	c := NewCPUAndMemory()
+	copy(c.Memory[:], image)
+	c.PC = functionalTestStart
+
+	// Every outcome ends in a jump to itself, so run until the PC sticks
+	for i := 0; i < functionalTestLimit; i++ {
+		pc := c.PC
+		c.Step()
+		if c.PC == pc {
+			if pc != success {
+				t.Fatalf("trapped at $%04X after %d instructions, expected success at $%04X", pc, i, success)
+			}
+			return
+		}
+	}
+	t.Fatalf("no trap after %d instructions, PC $%04X", functionalTestLimit, c.PC)
+}
@@ -0,0 +1,216 @@
+package cpu
+
+// illegalMode is the addressing mode of an undocumented opcode
+type illegalMode int
+
+const (
+	illegalImplied illegalMode = iota
+	illegalImmediate
+	illegalZeroPage
+	illegalZeroPageX
+	illegalZeroPageY
+	illegalAbsolute
+	illegalAbsoluteX
+	illegalAbsoluteY
+	illegalIndirectX
+	illegalIndirectY
+)
+
+// IllegalOpcode describes one undocumented opcode executed when
+// CPU.EnableIllegal is set
+type IllegalOpcode struct {
+	Name   string
+	mode   illegalMode
+	Cycles uint8 // Base cycles; LAX and NOP add one on a page crossing
+}
+
+// IllegalOpcodes lists the stable undocumented NMOS opcodes implemented
+var IllegalOpcodes = map[uint8]IllegalOpcode{
+	0x02: {"KIL", illegalImplied, 0},
+	0x03: {"SLO", illegalIndirectX, 8},
+	0x04: {"NOP", illegalZeroPage, 3},
+	0x07: {"SLO", illegalZeroPage, 5},
+	0x0C: {"NOP", illegalAbsolute, 4},
+	0x0F: {"SLO", illegalAbsolute, 6},
+	0x12: {"KIL", illegalImplied, 0},
+	0x13: {"SLO", illegalIndirectY, 8},
+	0x14: {"NOP", illegalZeroPageX, 4},
+	0x17: {"SLO", illegalZeroPageX, 6},
+	0x1A: {"NOP", illegalImplied, 2},
+	0x1B: {"SLO", illegalAbsoluteY, 7},
+	0x1C: {"NOP", illegalAbsoluteX, 4},
+	0x1F: {"SLO", illegalAbsoluteX, 7},
+	0x22: {"KIL", illegalImplied, 0},
+	0x23: {"RLA", illegalIndirectX, 8},
+	0x27: {"RLA", illegalZeroPage, 5},
+	0x2F: {"RLA", illegalAbsolute, 6},
+	0x32: {"KIL", illegalImplied, 0},
+	0x33: {"RLA", illegalIndirectY, 8},
+	0x34: {"NOP", illegalZeroPageX, 4},
+	0x37: {"RLA", illegalZeroPageX, 6},
+	0x3A: {"NOP", illegalImplied, 2},
+	0x3B: {"RLA", illegalAbsoluteY, 7},
+	0x3C: {"NOP", illegalAbsoluteX, 4},
+	0x3F: {"RLA", illegalAbsoluteX, 7},
+	0x42: {"KIL", illegalImplied, 0},
+	0x43: {"SRE", illegalIndirectX, 8},
+	0x44: {"NOP", illegalZeroPage, 3},
+	0x47: {"SRE", illegalZeroPage, 5},
+	0x4F: {"SRE", illegalAbsolute, 6},
+	0x52: {"KIL", illegalImplied, 0},
+	0x53: {"SRE", illegalIndirectY, 8},
+	0x54: {"NOP", illegalZeroPageX, 4},
+	0x57: {"SRE", illegalZeroPageX, 6},
+	0x5A: {"NOP", illegalImplied, 2},
+	0x5B: {"SRE", illegalAbsoluteY, 7},
+	0x5C: {"NOP", illegalAbsoluteX, 4},
+	0x5F: {"SRE", illegalAbsoluteX, 7},
+	0x62: {"KIL", illegalImplied, 0},
+	0x63: {"RRA", illegalIndirectX, 8},
+	0x64: {"NOP", illegalZeroPage, 3},
+	0x67: {"RRA", illegalZeroPage, 5},
+	0x6F: {"RRA", illegalAbsolute, 6},
+	0x72: {"KIL", illegalImplied, 0},
+	0x73: {"RRA", illegalIndirectY, 8},
+	0x74: {"NOP", illegalZeroPageX, 4},
+	0x77: {"RRA", illegalZeroPageX, 6},
+	0x7A: {"NOP", illegalImplied, 2},
+	0x7B: {"RRA", illegalAbsoluteY, 7},
+	0x7C: {"NOP", illegalAbsoluteX, 4},
+	0x7F: {"RRA", illegalAbsoluteX, 7},
+	0x80: {"NOP", illegalImmediate, 2},
+	0x82: {"NOP", illegalImmediate, 2},
+	0x83: {"SAX", illegalIndirectX, 6},
+	0x87: {"SAX", illegalZeroPage, 3},
+	0x89: {"NOP", illegalImmediate, 2},
+	0x8F: {"SAX", illegalAbsolute, 4},
+	0x92: {"KIL", illegalImplied, 0},
+	0x97: {"SAX", illegalZeroPageY, 4},
+	0xA3: {"LAX", illegalIndirectX, 6},
+	0xA7: {"LAX", illegalZeroPage, 3},
+	0xAF: {"LAX", illegalAbsolute, 4},
+	0xB2: {"KIL", illegalImplied, 0},
+	0xB3: {"LAX", illegalIndirectY, 5},
+	0xB7: {"LAX", illegalZeroPageY, 4},
+	0xBF: {"LAX", illegalAbsoluteY, 4},
+	0xC2: {"NOP", illegalImmediate, 2},
+	0xC3: {"DCP", illegalIndirectX, 8},
+	0xC7: {"DCP", illegalZeroPage, 5},
+	0xCF: {"DCP", illegalAbsolute, 6},
+	0xD2: {"KIL", illegalImplied, 0},
+	0xD3: {"DCP", illegalIndirectY, 8},
+	0xD4: {"NOP", illegalZeroPageX, 4},
+	0xD7: {"DCP", illegalZeroPageX, 6},
+	0xDA: {"NOP", illegalImplied, 2},
+	0xDB: {"DCP", illegalAbsoluteY, 7},
+	0xDC: {"NOP", illegalAbsoluteX, 4},
+	0xDF: {"DCP", illegalAbsoluteX, 7},
+	0xE2: {"NOP", illegalImmediate, 2},
+	0xE3: {"ISC", illegalIndirectX, 8},
+	0xE7: {"ISC", illegalZeroPage, 5},
+	0xEF: {"ISC", illegalAbsolute, 6},
+	0xF2: {"KIL", illegalImplied, 0},
+	0xF3: {"ISC", illegalIndirectY, 8},
+	0xF4: {"NOP", illegalZeroPageX, 4},
+	0xF7: {"ISC", illegalZeroPageX, 6},
+	0xFA: {"NOP", illegalImplied, 2},
+	0xFB: {"ISC", illegalAbsoluteY, 7},
+	0xFC: {"NOP", illegalAbsoluteX, 4},
+	0xFF: {"ISC", illegalAbsoluteX, 7},
+}
+
+// executeIllegal runs an undocumented opcode, reporting false if it is not
+// one of IllegalOpcodes
+func (c *CPU) executeIllegal(opcode uint8) (uint8, bool) {
+	op, exists := IllegalOpcodes[opcode]
+	if !exists {
+		return 0, false
+	}
+
+	if op.Name == "KIL" {
+		// The real chip locks up with the bus held; stay on the opcode
+		c.Jammed = true
+		c.PC--
+		return 1, true
+	}
+
+	addr, crossed := c.illegalAddress(op.mode)
+	cycles := op.Cycles
+
+	switch op.Name {
+	case "NOP":
+		if op.mode != illegalImplied && op.mode != illegalImmediate {
+			c.Read(addr)
+		}
+		if crossed && op.mode == illegalAbsoluteX {
+			cycles++
+		}
+	case "LAX":
+		c.A = c.Read(addr)
+		c.X = c.A
+		c.updateZN(c.A)
+		if crossed && (op.mode == illegalAbsoluteY || op.mode == illegalIndirectY) {
+			cycles++
+		}
+	case "SAX":
+		c.Write(addr, c.A&c.X)
+	case "SLO":
+		value := c.asl(c.Read(addr))
+		c.Write(addr, value)
+		c.A |= value
+		c.updateZN(c.A)
+	case "RLA":
+		value := c.rol(c.Read(addr))
+		c.Write(addr, value)
+		c.A &= value
+		c.updateZN(c.A)
+	case "SRE":
+		value := c.lsr(c.Read(addr))
+		c.Write(addr, value)
+		c.A ^= value
+		c.updateZN(c.A)
+	case "RRA":
+		value := c.ror(c.Read(addr))
+		c.Write(addr, value)
+		c.adc(value)
+	case "DCP":
+		value := c.Read(addr) - 1
+		c.Write(addr, value)
+		c.cmp(value)
+	case "ISC":
+		value := c.Read(addr) + 1
+		c.Write(addr, value)
+		c.sbc(value)
+	}
+	return cycles, true
+}
+
+// illegalAddress fetches the operand for mode and returns the effective
+// address and whether indexing crossed a page, making the same dummy read on
+// a page crossing as the documented indexed modes. Implied mode has none, and
+// immediate mode addresses the operand byte itself.
+func (c *CPU) illegalAddress(mode illegalMode) (uint16, bool) {
+	switch mode {
+	case illegalImmediate:
+		addr := c.PC
+		c.PC++
+		return addr, false
+	case illegalZeroPage:
+		return uint16(c.readImmediate()), false
+	case illegalZeroPageX:
+		return uint16(c.readImmediate() + c.X), false
+	case illegalZeroPageY:
+		return uint16(c.readImmediate() + c.Y), false
+	case illegalAbsolute:
+		return c.readAbsoluteAddress(), false
+	case illegalAbsoluteX:
+		return c.indexedAddress(c.readAbsoluteAddress(), c.X)
+	case illegalAbsoluteY:
+		return c.indexedAddress(c.readAbsoluteAddress(), c.Y)
+	case illegalIndirectX:
+		return c.readIndirectAddress(c.readImmediate() + c.X), false
+	case illegalIndirectY:
+		return c.indexedAddress(c.readIndirectAddress(c.readImmediate()), c.Y)
+	}
+	return 0, false
+}
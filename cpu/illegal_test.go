@@ -0,0 +1,205 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIllegalOpcodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		setup   func(*CPUAndMemory)
+		cycles  uint8
+		check   func(*testing.T, *CPUAndMemory)
+	}{
+		{
+			name:    "LAX zero page",
+			program: []uint8{0xA7, 0x40},
+			setup:   func(c *CPUAndMemory) { c.Memory[0x40] = 0x80 },
+			cycles:  3,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x80), c.A)
+				assert.Equal(t, uint8(0x80), c.X)
+				assert.True(t, c.P&FlagN != 0)
+			},
+		},
+		{
+			name:    "LAX absolute,Y page cross",
+			program: []uint8{0xBF, 0xFF, 0x20},
+			setup: func(c *CPUAndMemory) {
+				c.Y = 0x01
+				c.Memory[0x2100] = 0x00
+			},
+			cycles: 5,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x00), c.X)
+				assert.True(t, c.P&FlagZ != 0)
+			},
+		},
+		{
+			name:    "SAX absolute",
+			program: []uint8{0x8F, 0x00, 0x03},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0xF0
+				c.X = 0x3C
+			},
+			cycles: 4,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x30), c.Memory[0x0300])
+			},
+		},
+		{
+			name:    "DCP zero page equal after decrement",
+			program: []uint8{0xC7, 0x40},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x41
+				c.Memory[0x40] = 0x42
+			},
+			cycles: 5,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x41), c.Memory[0x40])
+				assert.True(t, c.P&FlagZ != 0)
+				assert.True(t, c.P&FlagC != 0)
+			},
+		},
+		{
+			name:    "ISC absolute,X",
+			program: []uint8{0xFF, 0x00, 0x03},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x10
+				c.X = 0x02
+				c.P |= FlagC
+				c.Memory[0x0302] = 0x04
+			},
+			cycles: 7,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x05), c.Memory[0x0302])
+				assert.Equal(t, uint8(0x0B), c.A)
+			},
+		},
+		{
+			name:    "SLO zero page",
+			program: []uint8{0x07, 0x40},
+			setup: func(c *CPUAndMemory) {
+				c.A = 0x01
+				c.Memory[0x40] = 0x81
+			},
+			cycles: 5,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint8(0x02), c.Memory[0x40])
+				assert.Equal(t, uint8(0x03), c.A)
+				assert.True(t, c.P&FlagC != 0)
+			},
+		},
+		{
+			name:    "NOP absolute,X page cross",
+			program: []uint8{0x1C, 0xFF, 0x20},
+			setup:   func(c *CPUAndMemory) { c.X = 0x01 },
+			cycles:  5,
+			check: func(t *testing.T, c *CPUAndMemory) {
+				assert.Equal(t, uint16(0x1003), c.PC)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCPUAndMemory()
+			c.EnableIllegal = true
+			copy(c.Memory[0x1000:], tt.program)
+			c.PC = 0x1000
+			tt.setup(c)
+
+			assert.Equal(t, tt.cycles, c.Step())
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestKILJams(t *testing.T) {
+	c := NewCPUAndMemory()
+	c.EnableIllegal = true
+	c.Memory[0x1000] = 0x02
+	c.PC = 0x1000
+
+	c.Step()
+	c.Step()
+
+	assert.True(t, c.Jammed)
+	assert.Equal(t, uint16(0x1000), c.PC)
+}
+
+func TestIllegalOpcodesDisabled(t *testing.T) {
+	c := NewCPUAndMemory()
+	c.Memory[0x1000] = 0xA7
+	c.PC = 0x1000
+
+	assert.Panics(t, func() { c.Step() })
+}
+
+func TestIllegalIndexedDummyRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		setup   func(*CPU, *spyBus)
+		reads   []uint16
+		cycles  uint8
+	}{
+		{
+			name:    "LAX absolute,Y page cross",
+			program: []uint8{0xBF, 0xFF, 0x12},
+			setup:   func(c *CPU, _ *spyBus) { c.Y = 0x01 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0x1200, 0x1300},
+			cycles:  5,
+		},
+		{
+			name:    "LAX absolute,Y no page cross",
+			program: []uint8{0xBF, 0x10, 0x12},
+			setup:   func(c *CPU, _ *spyBus) { c.Y = 0x01 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0x1211},
+			cycles:  4,
+		},
+		{
+			name:    "LAX (indirect),Y page cross",
+			program: []uint8{0xB3, 0x40},
+			setup: func(c *CPU, b *spyBus) {
+				b.Memory[0x40] = 0xF0
+				b.Memory[0x41] = 0xDC
+				c.Y = 0x20
+			},
+			reads:  []uint16{0x1000, 0x1001, 0x0040, 0x0041, 0xDC10, 0xDD10},
+			cycles: 6,
+		},
+		{
+			name:    "NOP absolute,X page cross",
+			program: []uint8{0x1C, 0x80, 0xD0},
+			setup:   func(c *CPU, _ *spyBus) { c.X = 0x90 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0xD010, 0xD110},
+			cycles:  5,
+		},
+		{
+			name:    "SLO absolute,X page cross",
+			program: []uint8{0x1F, 0xFF, 0x12},
+			setup:   func(c *CPU, _ *spyBus) { c.X = 0x01 },
+			reads:   []uint16{0x1000, 0x1001, 0x1002, 0x1200, 0x1300},
+			cycles:  7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &spyBus{}
+			c := NewCPU(bus)
+			c.EnableIllegal = true
+			copy(bus.Memory[0x1000:], tt.program)
+			c.PC = 0x1000
+			tt.setup(c, bus)
+
+			cycles := c.Step()
+
+			assert.Equal(t, tt.cycles, cycles)
+			assert.Equal(t, tt.reads, bus.reads())
+		})
+	}
+}
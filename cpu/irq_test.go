@@ -0,0 +1,129 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newIRQTestCPU(program ...uint8) *CPUAndMemory {
+	c := NewCPUAndMemory()
+	copy(c.Memory[0x1000:], program)
+	c.PC = 0x1000
+	// IRQ handler at $2000
+	c.Memory[0xFFFE] = 0x00
+	c.Memory[0xFFFF] = 0x20
+	return c
+}
+
+func TestIRQTakenAtInstructionBoundary(t *testing.T) {
+	c := newIRQTestCPU(LDA_IMM, 0x01, LDA_IMM, 0x02)
+	c.P = 0x20 // I clear
+
+	c.Step()
+	c.SetIRQ(true)
+	cycles := c.Step()
+
+	assert.Equal(t, uint8(7), cycles)
+	assert.Equal(t, uint16(0x2000), c.PC)
+	assert.Equal(t, uint8(0x01), c.A, "second LDA should not have run")
+	assert.True(t, c.P&FlagI != 0, "I flag should be set in the handler")
+
+	// Return address is the interrupted instruction
+	assert.Equal(t, uint8(0x10), c.Memory[0x01FF])
+	assert.Equal(t, uint8(0x02), c.Memory[0x01FE])
+}
+
+func TestIRQDelayedAfterCLI(t *testing.T) {
+	c := newIRQTestCPU(
+		CLI,
+		LDA_IMM, 0x01,
+		LDA_IMM, 0x02,
+	)
+	c.P = 0x20 | FlagI
+	c.SetIRQ(true)
+
+	c.Step() // CLI
+	assert.Equal(t, uint16(0x1001), c.PC)
+
+	// The instruction following CLI still executes before the IRQ
+	c.Step()
+	assert.Equal(t, uint16(0x1003), c.PC)
+	assert.Equal(t, uint8(0x01), c.A)
+
+	cycles := c.Step()
+	assert.Equal(t, uint8(7), cycles)
+	assert.Equal(t, uint16(0x2000), c.PC)
+	assert.Equal(t, uint8(0x01), c.A)
+	assert.Equal(t, uint8(0x10), c.Memory[0x01FF])
+	assert.Equal(t, uint8(0x03), c.Memory[0x01FE])
+}
+
+func TestIRQTakenAfterSEI(t *testing.T) {
+	c := newIRQTestCPU(SEI, LDA_IMM, 0x01)
+	c.P = 0x20
+
+	c.Step() // SEI
+	c.SetIRQ(true)
+
+	// The poll during SEI saw I clear, so the IRQ is still taken
+	c.Step()
+	assert.Equal(t, uint16(0x2000), c.PC)
+	assert.Equal(t, uint8(0x00), c.A)
+}
+
+func TestIRQMaskedByIFlag(t *testing.T) {
+	c := newIRQTestCPU(LDA_IMM, 0x01, LDA_IMM, 0x02)
+	c.P = 0x20 | FlagI
+	c.SetIRQ(true)
+
+	c.Step()
+	c.Step()
+	assert.Equal(t, uint16(0x1004), c.PC)
+	assert.Equal(t, uint8(0x02), c.A)
+}
+
+func TestIRQRequest(t *testing.T) {
+	c := newIRQTestCPU(LDA_IMM, 0x01)
+	c.P = 0x20 // I clear
+
+	c.IRQ()
+	assert.True(t, c.InterruptPending())
+	cycles := c.Step()
+
+	assert.Equal(t, uint8(7), cycles)
+	assert.Equal(t, uint16(0x2000), c.PC)
+	assert.False(t, c.InterruptPending())
+	// Pushed status has B clear, unlike BRK
+	assert.Equal(t, uint8(0x20), c.Memory[0x01FD])
+}
+
+func TestIRQRequestBlockedBySEI(t *testing.T) {
+	c := newIRQTestCPU(SEI, LDA_IMM, 0x01, LDA_IMM, 0x02)
+	c.P = 0x20
+
+	c.Step()
+	c.Step() // SEI's effect is delayed one instruction
+	c.IRQ()
+	c.Step()
+
+	assert.Equal(t, uint16(0x1005), c.PC)
+	assert.Equal(t, uint8(0x02), c.A)
+	assert.False(t, c.InterruptPending(), "request is dropped while masked")
+}
+
+func TestNMIIgnoresIFlag(t *testing.T) {
+	c := newIRQTestCPU(LDA_IMM, 0x01)
+	c.Memory[0xFFFA] = 0x00
+	c.Memory[0xFFFB] = 0x30
+	c.P = 0x24 | FlagC // I set
+
+	c.NMI()
+	cycles := c.Step()
+
+	assert.Equal(t, uint8(7), cycles)
+	assert.Equal(t, uint16(0x3000), c.PC)
+	assert.True(t, c.P&FlagI != 0)
+	assert.Equal(t, uint8(0x10), c.Memory[0x01FF])
+	assert.Equal(t, uint8(0x00), c.Memory[0x01FE])
+	assert.Equal(t, uint8(0x25), c.Memory[0x01FD], "B clear in the pushed status")
+}
@@ -0,0 +1,41 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestCPUIsolation steps two CPUs on separate buses in lockstep, one with a
+// pending interrupt, and checks that neither observes the other's state. All
+// per-CPU state (registers, IRQ line, interrupt delay) lives on the instance.
+func TestCPUIsolation(t *testing.T) {
+	program := []uint8{
+		CLI,
+		LDA_IMM, 0x11,
+		STA_ABS, 0x00, 0x03,
+	}
+
+	a := newIRQTestCPU(program...)
+	bus := &spyBus{}
+	copy(bus.Memory[0x1000:], program)
+	bus.Memory[0x1002] = 0x22 // different immediate for the second CPU
+	b := NewCPU(bus)
+	b.PC = 0x1000
+
+	a.SetIRQ(true)
+
+	for i := 0; i < 3; i++ {
+		a.Step()
+		b.Step()
+	}
+
+	// a took its IRQ one instruction after CLI; b ran straight through
+	assert.Equal(t, uint16(0x2000), a.PC)
+	assert.Equal(t, uint8(0x11), a.A)
+	assert.Equal(t, uint8(0x00), a.Memory[0x0300])
+
+	assert.Equal(t, uint16(0x1006), b.PC)
+	assert.Equal(t, uint8(0x22), b.A)
+	assert.Equal(t, uint8(0x22), bus.Memory[0x0300])
+	assert.Equal(t, uint8(0xFF), b.SP, "b never pushed an interrupt frame")
+}
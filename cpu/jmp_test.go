@@ -57,6 +57,22 @@ func TestJumpInstructions(t *testing.T) {
 			},
 			cycles: 5,
 		},
+		{
+			name: "JMP Indirect Vector At $30FF",
+			setup: func(c *CPUAndMemory) {
+				c.Memory[0x0000] = JMP_IND
+				c.Memory[0x0001] = 0xFF
+				c.Memory[0x0002] = 0x30 // Indirect address: 0x30FF
+				c.Memory[0x30FF] = 0x80
+				c.Memory[0x3000] = 0x40 // High byte from the same page
+				c.Memory[0x3100] = 0x50 // Not from the next page
+			},
+			opcode: JMP_IND,
+			verify: func(c *CPUAndMemory, t *testing.T) {
+				assert.Equal(t, uint16(0x4080), c.PC, "PC should be 0x4080")
+			},
+			cycles: 5,
+		},
 		{
 			name: "JSR Absolute",
 			setup: func(c *CPUAndMemory) {
@@ -109,3 +125,23 @@ func TestJumpInstructions(t *testing.T) {
 		})
 	}
 }
+
+// Zero-page pointers wrap to $00, unlike the JMP vector's same-page wrap,
+// which only coincides with it on page zero.
+func TestIndirectPointerWrap(t *testing.T) {
+	c := NewCPUAndMemory()
+	copy(c.Memory[0x0200:], []uint8{LDA_INY, 0xFF, LDA_INX, 0xFE})
+	c.Memory[0x00FF] = 0x34
+	c.Memory[0x0000] = 0x12 // High byte of the pointer at $FF
+	c.Memory[0x0100] = 0x99 // Not read
+	c.Memory[0x1234] = 0xAB
+	c.PC = 0x0200
+
+	c.Step()
+	assert.Equal(t, uint8(0xAB), c.A, "(zp),Y")
+
+	c.A = 0
+	c.X = 1 // $FE+1 = $FF
+	c.Step()
+	assert.Equal(t, uint8(0xAB), c.A, "(zp,X)")
+}
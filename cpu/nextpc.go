@@ -0,0 +1,72 @@
+package cpu
+
+// NextPCs statically walks forward from PC and returns the addresses of up
+// to count instructions, starting with the one at PC. The walk follows
+// straight-line code: branches and JSR fall through to the next instruction,
+// while JMP, RTS, RTI and BRK end it. On the 65C02, JMP (abs,X) ends it too,
+// and BRA, which is always taken, ends it after adding its target. Opcodes
+// are peeked at, so the walk has no bus side effects, and nothing is executed.
+func (c *CPU) NextPCs(count int) []uint16 {
+	pcs := make([]uint16, 0, count)
+	pc := c.PC
+	for len(pcs) < count {
+		pcs = append(pcs, pc)
+		opcode := Peek(c.Bus, pc)
+		switch opcode {
+		case JMP_ABS, JMP_IND, RTS, RTI, BRK:
+			return pcs
+		}
+		if c.cmos() {
+			switch opcode {
+			case JMP_IAX:
+				return pcs
+			case BRA:
+				if len(pcs) < count {
+					pcs = append(pcs, pc+2+uint16(int8(Peek(c.Bus, pc+1))))
+				}
+				return pcs
+			}
+		}
+		pc += uint16(instructionSize(opcode))
+	}
+	return pcs
+}
+
+// instructionSize returns the length in bytes of a documented opcode from
+// its addressing-mode bits (aaabbbcc). Undocumented opcodes count as 1.
+func instructionSize(opcode uint8) int {
+	bbb := (opcode >> 2) & 0x07
+	switch opcode & 0x03 {
+	case 0x01:
+		// ALU group: (zp,X) zp #imm abs (zp),Y zp,X abs,Y abs,X
+		if bbb == 3 || bbb >= 6 {
+			return 3
+		}
+		return 2
+	case 0x02:
+		switch bbb {
+		case 2, 6: // accumulator, TXS/TSX and friends
+			return 1
+		case 3, 7:
+			return 3
+		}
+		return 2
+	case 0x00:
+		switch bbb {
+		case 0:
+			switch opcode {
+			case JSR_ABS:
+				return 3
+			case LDY_IMM, CPY_IMM, CPX_IMM, BRA:
+				return 2
+			}
+			return 1 // BRK, RTI, RTS
+		case 2, 6: // stack and flag instructions
+			return 1
+		case 3, 7:
+			return 3
+		}
+		return 2
+	}
+	return 1
+}
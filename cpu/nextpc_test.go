@@ -0,0 +1,85 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNextPCs(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpu      Variant
+		program  []uint8
+		count    int
+		expected []uint16
+	}{
+		{
+			name: "stops at JMP",
+			program: []uint8{
+				LDA_IMM, 0x01, // $1000
+				STA_ABS, 0x00, 0x02, // $1002
+				BNE, 0xFA, // $1005 falls through
+				JSR_ABS, 0xD2, 0xFF, // $1007 returns here
+				JMP_ABS, 0x00, 0x10, // $100A
+				NOP,
+			},
+			count:    10,
+			expected: []uint16{0x1000, 0x1002, 0x1005, 0x1007, 0x100A},
+		},
+		{
+			name:     "stops at RTS",
+			program:  []uint8{INX, LDY_IMM, 0x00, RTS, NOP},
+			count:    10,
+			expected: []uint16{0x1000, 0x1001, 0x1003},
+		},
+		{
+			name:     "BRA ends at its target",
+			cpu:      CMOS65C02,
+			program:  []uint8{INX, BRA, 0x10, NOP},
+			count:    10,
+			expected: []uint16{0x1000, 0x1001, 0x1013},
+		},
+		{
+			name:     "BRA is a 2-byte NOP on the NMOS 6502",
+			program:  []uint8{INX, BRA, 0x10, RTS},
+			count:    10,
+			expected: []uint16{0x1000, 0x1001, 0x1003},
+		},
+		{
+			name:     "stops at JMP (abs,X)",
+			cpu:      CMOS65C02,
+			program:  []uint8{JMP_IAX, 0x00, 0x20, NOP},
+			count:    10,
+			expected: []uint16{0x1000},
+		},
+		{
+			name:     "limited by count",
+			program:  []uint8{NOP, LDA_ABX, 0x00, 0x20, NOP, NOP},
+			count:    3,
+			expected: []uint16{0x1000, 0x1001, 0x1004},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCPUAndMemory()
+			c.Variant = tt.cpu
+			copy(c.Memory[0x1000:], tt.program)
+			c.PC = 0x1000
+
+			assert.Equal(t, tt.expected, c.NextPCs(tt.count))
+			assert.Equal(t, uint16(0x1000), c.PC, "walk must not execute")
+		})
+	}
+}
+
+func TestNextPCsAddsNoBusAccesses(t *testing.T) {
+	bus := &spyBus{}
+	copy(bus.Memory[0x1000:], []uint8{LDA_IMM, 0x01, BRA, 0x10})
+	c := NewCPU(bus)
+	c.Variant = CMOS65C02
+	c.PC = 0x1000
+
+	assert.Equal(t, []uint16{0x1000, 0x1002, 0x1014}, c.NextPCs(4))
+	assert.Empty(t, bus.Accesses)
+}
@@ -0,0 +1,53 @@
+package cpu
+
+// ObservedBus wraps a MemoryBus and reports accesses to observers, so tools
+// like the monitor can implement watchpoints. Without observers it passes
+// accesses straight through.
+type ObservedBus struct {
+	Bus MemoryBus
+
+	onRead  func(addr uint16, value uint8)
+	onWrite func(addr uint16, old, new uint8)
+}
+
+// NewObservedBus wraps bus with no observer set
+func NewObservedBus(bus MemoryBus) *ObservedBus {
+	return &ObservedBus{Bus: bus}
+}
+
+// SetWriteObserver calls fn after each write with the value the address held
+// beforehand. A nil fn removes the observer. The old value is fetched with
+// Peek, so buses implementing Peeker see no extra access; others get an extra
+// Read while an observer is set.
+func (o *ObservedBus) SetWriteObserver(fn func(addr uint16, old, new uint8)) {
+	o.onWrite = fn
+}
+
+// SetReadObserver calls fn after each read with the value read. A nil fn
+// removes the observer.
+func (o *ObservedBus) SetReadObserver(fn func(addr uint16, value uint8)) {
+	o.onRead = fn
+}
+
+func (o *ObservedBus) Read(address uint16) uint8 {
+	value := o.Bus.Read(address)
+	if o.onRead != nil {
+		o.onRead(address, value)
+	}
+	return value
+}
+
+// Peek reads address without notifying the read observer
+func (o *ObservedBus) Peek(address uint16) uint8 {
+	return Peek(o.Bus, address)
+}
+
+func (o *ObservedBus) Write(address uint16, value uint8) {
+	if o.onWrite == nil {
+		o.Bus.Write(address, value)
+		return
+	}
+	old := Peek(o.Bus, address)
+	o.Bus.Write(address, value)
+	o.onWrite(address, old, value)
+}
@@ -0,0 +1,74 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type write struct {
+	addr     uint16
+	old, new uint8
+}
+
+func TestObservedBusWrites(t *testing.T) {
+	mem := &spyBus{}
+	mem.Memory[0x0300] = 0x11
+	copy(mem.Memory[0x1000:], []uint8{LDA_IMM, 0x42, STA_ABS, 0x00, 0x03, INC_ABS, 0x00, 0x03})
+	bus := NewObservedBus(mem)
+	c := NewCPU(bus)
+	c.PC = 0x1000
+
+	var writes []write
+	bus.SetWriteObserver(func(addr uint16, old, new uint8) {
+		writes = append(writes, write{addr, old, new})
+	})
+	c.Step()
+	c.Step()
+	c.Step()
+
+	assert.Equal(t, []write{
+		{0x0300, 0x11, 0x42},
+		{0x0300, 0x42, 0x43},
+	}, writes)
+	assert.Equal(t, uint8(0x43), mem.Memory[0x0300])
+
+	// Removing the observer stops notifications but not writes
+	bus.SetWriteObserver(nil)
+	bus.Write(0x0301, 0x99)
+	assert.Len(t, writes, 2)
+	assert.Equal(t, uint8(0x99), mem.Memory[0x0301])
+}
+
+func TestObservedBusReads(t *testing.T) {
+	mem := &spyBus{}
+	mem.Memory[0x0300] = 0x5A
+	bus := NewObservedBus(mem)
+
+	var reads []uint16
+	bus.SetReadObserver(func(addr uint16, value uint8) {
+		assert.Equal(t, mem.Memory[addr], value)
+		reads = append(reads, addr)
+	})
+	assert.Equal(t, uint8(0x5A), bus.Read(0x0300))
+	bus.Write(0x0301, 0x01) // no write observer, so no read-back either
+	assert.Equal(t, []uint16{0x0300}, reads)
+
+	bus.SetReadObserver(nil)
+	bus.Read(0x0300)
+	assert.Len(t, reads, 1)
+}
+
+func TestObservedBusOldValueAddsNoRead(t *testing.T) {
+	mem := &spyBus{}
+	mem.Memory[0xDC0D] = 0x81
+	bus := NewObservedBus(mem)
+
+	var writes []write
+	bus.SetWriteObserver(func(addr uint16, old, new uint8) {
+		writes = append(writes, write{addr, old, new})
+	})
+	bus.Write(0xDC0D, 0x7F)
+
+	assert.Equal(t, []write{{0xDC0D, 0x81, 0x7F}}, writes)
+	assert.Empty(t, mem.reads(), "a read could clear a latch such as the CIA ICR")
+}
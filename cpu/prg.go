@@ -0,0 +1,27 @@
+package cpu
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errShortPRG is returned for PRG data too short to hold its load address
+var errShortPRG = errors.New("PRG file is missing its load address")
+
+// LoadPRG copies a C64 .prg file into mem. The first two bytes are the
+// little-endian load address; the rest is copied there and returned start
+// is that address. Nothing else, such as the reset vectors, is touched.
+func LoadPRG(mem MemoryBus, data []byte) (start uint16, err error) {
+	if len(data) < 2 {
+		return 0, errShortPRG
+	}
+	start = uint16(data[0]) | uint16(data[1])<<8
+	body := data[2:]
+	if int(start)+len(body) > 0x10000 {
+		return 0, fmt.Errorf("PRG at $%04X is %d bytes, past the end of memory", start, len(body))
+	}
+	for i, b := range body {
+		mem.Write(start+uint16(i), b)
+	}
+	return start, nil
+}
@@ -0,0 +1,41 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadPRG(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []uint8
+		start uint16
+		err   bool
+	}{
+		{name: "BASIC start", data: []uint8{0x01, 0x08, 0x0B, 0x08, 0x0A, 0x00}, start: 0x0801},
+		{name: "machine code", data: []uint8{0x00, 0xC0, LDA_IMM, 0x01, RTS}, start: 0xC000},
+		{name: "header only", data: []uint8{0x00, 0x20}, start: 0x2000},
+		{name: "ends at $FFFF", data: []uint8{0xFE, 0xFF, 0x11, 0x22}, start: 0xFFFE},
+		{name: "past $FFFF", data: []uint8{0xFF, 0xFF, 0x11, 0x22}, err: true},
+		{name: "no header", data: []uint8{0x01}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCPUAndMemory()
+			c.Memory[0xFFFC], c.Memory[0xFFFD] = 0x34, 0x12
+			start, err := LoadPRG(c, tt.data)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.start, start)
+			body := tt.data[2:]
+			assert.Equal(t, body, c.Memory[int(start):int(start)+len(body)])
+			if start < 0xFFFC {
+				assert.Equal(t, []uint8{0x34, 0x12}, c.Memory[0xFFFC:0xFFFE], "vectors are left alone")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package cpu
+
+import "sort"
+
+// ProfileEntry is how often the instruction at PC ran and the cycles it
+// took in total
+type ProfileEntry struct {
+	PC     uint16
+	Count  uint64
+	Cycles uint64
+}
+
+// Profiler accumulates per-PC execution counts and cycles. Assign one to
+// CPU.Profiler to start profiling; a nil Profiler costs Step nothing.
+type Profiler struct {
+	count  [0x10000]uint64
+	cycles [0x10000]uint64
+}
+
+// NewProfiler returns an empty Profiler
+func NewProfiler() *Profiler {
+	return &Profiler{}
+}
+
+// record charges cycles to the instruction at pc. Step only records
+// instructions that ran, so interrupt entries, stalls and idle cycles are
+// not charged to any PC.
+func (p *Profiler) record(pc uint16, cycles uint8) {
+	p.count[pc]++
+	p.cycles[pc] += uint64(cycles)
+}
+
+// Reset clears the accumulated counts
+func (p *Profiler) Reset() {
+	*p = Profiler{}
+}
+
+// Report returns an entry for every PC that executed, most cycles first and
+// by address among equals
+func (p *Profiler) Report() []ProfileEntry {
+	var entries []ProfileEntry
+	for pc, count := range p.count {
+		if count > 0 {
+			entries = append(entries, ProfileEntry{PC: uint16(pc), Count: count, Cycles: p.cycles[pc]})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Cycles != entries[j].Cycles {
+			return entries[i].Cycles > entries[j].Cycles
+		}
+		return entries[i].PC < entries[j].PC
+	})
+	return entries
+}
+
+// ProfileReport returns the Profiler's report, or nil when not profiling
+func (c *CPU) ProfileReport() []ProfileEntry {
+	if c.Profiler == nil {
+		return nil
+	}
+	return c.Profiler.Report()
+}
@@ -0,0 +1,66 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	c := NewCPUAndMemory()
+	copy(c.Memory[0x1000:], []uint8{
+		LDX_IMM, 0x10, // $1000 LDX #$10
+		STA_ABX, 0x00, 0x20, // $1002 STA $2000,X
+		DEX,       // $1005 DEX
+		BNE, 0xFA, // $1006 BNE $1002
+		NOP, // $1008 NOP
+	})
+	c.PC = 0x1000
+	assert.Nil(t, c.ProfileReport(), "profiling is off by default")
+
+	c.Profiler = NewProfiler()
+	for c.PC != 0x1009 {
+		c.Step()
+	}
+	report := c.ProfileReport()
+
+	// The loop body dominates, costliest first
+	if assert.Len(t, report, 5) {
+		assert.Equal(t, ProfileEntry{PC: 0x1002, Count: 16, Cycles: 80}, report[0])
+		assert.Equal(t, ProfileEntry{PC: 0x1006, Count: 16, Cycles: 15*3 + 2}, report[1])
+		assert.Equal(t, ProfileEntry{PC: 0x1005, Count: 16, Cycles: 32}, report[2])
+		assert.Equal(t, uint16(0x1000), report[3].PC)
+		assert.Equal(t, uint16(0x1008), report[4].PC)
+	}
+
+	var total uint64
+	for _, entry := range report {
+		total += entry.Cycles
+	}
+	assert.Equal(t, c.Cycles, total)
+
+	// Stalls are not charged to the instruction that follows them
+	c.Memory[0x1009] = NOP
+	c.Stall(10)
+	assert.Equal(t, uint8(12), c.Step())
+	assert.Equal(t, append(report, ProfileEntry{PC: 0x1009, Count: 1, Cycles: 2}), c.ProfileReport())
+
+	c.Profiler.Reset()
+	assert.Empty(t, c.ProfileReport())
+}
+
+func TestProfilerSkipsInterruptEntry(t *testing.T) {
+	c := newIRQTestCPU(LDA_IMM, 0x01, LDA_IMM, 0x02)
+	c.Memory[0x2000] = NOP
+	c.P = 0x20 // I clear
+	c.Profiler = NewProfiler()
+
+	c.Step()
+	c.IRQ()
+	assert.Equal(t, uint8(7), c.Step())
+	c.Step()
+
+	assert.Equal(t, []ProfileEntry{
+		{PC: 0x1000, Count: 1, Cycles: 2},
+		{PC: 0x2000, Count: 1, Cycles: 2},
+	}, c.ProfileReport(), "the interrupted LDA at $1002 never ran")
+}
@@ -1,6 +1,7 @@
 package cpu
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -96,3 +97,68 @@ func TestRegisterIncDec(t *testing.T) {
 		}
 	}
 }
+
+// TestIncDecFlags checks that increments and decrements only ever touch N
+// and Z, leaving C and V exactly as they were.
+func TestIncDecFlags(t *testing.T) {
+	ops := []struct {
+		name   string
+		opcode uint8
+		inc    bool
+		setup  func(*CPUAndMemory, uint8)
+		result func(*CPUAndMemory) uint8
+	}{
+		{"INC_ZP", INC_ZP, true,
+			func(c *CPUAndMemory, v uint8) { c.Memory[1] = 0x42; c.Memory[0x42] = v },
+			func(c *CPUAndMemory) uint8 { return c.Memory[0x42] }},
+		{"INC_ABS", INC_ABS, true,
+			func(c *CPUAndMemory, v uint8) { c.Memory[1] = 0x80; c.Memory[2] = 0x12; c.Memory[0x1280] = v },
+			func(c *CPUAndMemory) uint8 { return c.Memory[0x1280] }},
+		{"DEC_ZP", DEC_ZP, false,
+			func(c *CPUAndMemory, v uint8) { c.Memory[1] = 0x42; c.Memory[0x42] = v },
+			func(c *CPUAndMemory) uint8 { return c.Memory[0x42] }},
+		{"DEC_ABS", DEC_ABS, false,
+			func(c *CPUAndMemory, v uint8) { c.Memory[1] = 0x80; c.Memory[2] = 0x12; c.Memory[0x1280] = v },
+			func(c *CPUAndMemory) uint8 { return c.Memory[0x1280] }},
+		{"INX", INX, true,
+			func(c *CPUAndMemory, v uint8) { c.X = v },
+			func(c *CPUAndMemory) uint8 { return c.X }},
+		{"INY", INY, true,
+			func(c *CPUAndMemory, v uint8) { c.Y = v },
+			func(c *CPUAndMemory) uint8 { return c.Y }},
+		{"DEX", DEX, false,
+			func(c *CPUAndMemory, v uint8) { c.X = v },
+			func(c *CPUAndMemory) uint8 { return c.X }},
+		{"DEY", DEY, false,
+			func(c *CPUAndMemory, v uint8) { c.Y = v },
+			func(c *CPUAndMemory) uint8 { return c.Y }},
+	}
+
+	// Boundary values: $00->$FF on DEC, $7F->$80 and $FF->$00 on INC, plus
+	// the reverse crossings.
+	values := []uint8{0x00, 0x01, 0x7F, 0x80, 0xFF}
+
+	for _, op := range ops {
+		for _, initial := range values {
+			for _, preset := range []uint8{0, FlagC, FlagV, FlagC | FlagV} {
+				name := fmt.Sprintf("%s_%02X_P%02X", op.name, initial, preset)
+				t.Run(name, func(t *testing.T) {
+					c := NewCPUAndMemory()
+					c.PC = 1
+					c.P = preset
+					op.setup(c, initial)
+					c.execute(op.opcode)
+
+					expected := initial + 1
+					if !op.inc {
+						expected = initial - 1
+					}
+					assert.Equal(t, expected, op.result(c))
+					assert.Equal(t, expected == 0, c.P&FlagZ != 0, "Zero flag mismatch")
+					assert.Equal(t, expected&0x80 != 0, c.P&FlagN != 0, "Negative flag mismatch")
+					assert.Equal(t, preset, c.P&(FlagC|FlagV), "C and V must be preserved")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRunUntilPredicate(t *testing.T) {
+	c := NewCPUAndMemory()
+	copy(c.Memory[0x1000:], []uint8{
+		CLC,
+		ADC_IMM, 0x01, // loop
+		JMP_ABS, 0x01, 0x10,
+	})
+	c.PC = 0x1000
+
+	cycles := c.Run(0, func(c *CPU) bool { return c.A == 0x05 })
+
+	assert.Equal(t, uint8(0x05), c.A)
+	assert.Equal(t, uint16(0x1003), c.PC, "stops before the next instruction")
+	assert.Equal(t, uint64(2+4*(2+3)+2), cycles) // CLC, four ADC/JMP rounds, final ADC
+}
+
+func TestRunStops(t *testing.T) {
+	t.Run("BRK", func(t *testing.T) {
+		c := NewCPUAndMemory()
+		copy(c.Memory[0x1000:], []uint8{INX, INX, BRK})
+		c.PC = 0x1000
+
+		assert.Equal(t, uint64(4), c.Run(0, nil))
+		assert.Equal(t, uint16(0x1002), c.PC)
+	})
+
+	t.Run("cycle budget", func(t *testing.T) {
+		c := NewCPUAndMemory()
+		copy(c.Memory[0x1000:], []uint8{JMP_ABS, 0x00, 0x10})
+		c.PC = 0x1000
+
+		assert.Equal(t, uint64(12), c.Run(10, nil))
+	})
+
+	t.Run("breakpoint on current PC", func(t *testing.T) {
+		c := NewCPUAndMemory()
+		copy(c.Memory[0x1000:], []uint8{INX})
+		c.PC = 0x1000
+
+		assert.Equal(t, uint64(0), c.Run(0, func(c *CPU) bool { return c.PC == 0x1000 }))
+		assert.Equal(t, uint8(0), c.X)
+	})
+
+	t.Run("WAI", func(t *testing.T) {
+		c := newIRQTestCPU(INX, WAI, INX)
+		c.Variant = CMOS65C02
+		c.Memory[0x2000] = INY
+		c.P = 0x20 // I clear
+
+		assert.Equal(t, uint64(2+3), c.Run(0, nil), "returns instead of idling forever")
+		assert.True(t, c.Halted())
+
+		c.SetIRQ(true)
+		c.SetIRQ(false)
+		c.IRQ()
+		c.Run(0, nil)
+		assert.Equal(t, uint8(1), c.Y, "resumes in the handler")
+	})
+
+	t.Run("jam", func(t *testing.T) {
+		c := NewCPUAndMemory()
+		c.EnableIllegal = true
+		copy(c.Memory[0x1000:], []uint8{INX, 0x02, INX})
+		c.PC = 0x1000
+
+		c.Run(0, nil)
+		assert.True(t, c.Jammed)
+		assert.Equal(t, uint8(1), c.X)
+	})
+}
+
+func TestRunBRKCheckAddsNoBusAccess(t *testing.T) {
+	mem := &spyBus{}
+	copy(mem.Memory[0x1000:], []uint8{INX, INX, BRK})
+	c := NewCPU(mem)
+	c.PC = 0x1000
+
+	c.Run(0, nil)
+	assert.Equal(t, uint16(0x1002), c.PC)
+	assert.Equal(t, []uint16{0x1000, 0x1001}, mem.reads(), "only the INX fetches")
+}
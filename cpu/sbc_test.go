@@ -135,3 +135,61 @@ func TestSBCAddressingModes(t *testing.T) {
 		})
 	}
 }
+
+func TestSBCDecimalMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		accumulator uint8
+		operand     uint8
+		carryIn     bool
+		expected    uint8
+		expectC     bool
+	}{
+		{"simple subtraction", 0x46, 0x12, true, 0x34, true},
+		{"borrow in", 0x46, 0x12, false, 0x33, true},
+		{"low digit borrow", 0x40, 0x01, true, 0x39, true},
+		{"wraps below zero", 0x00, 0x01, true, 0x99, false},
+		{"high digit borrow", 0x12, 0x21, true, 0x91, false},
+		{"equal values", 0x50, 0x50, true, 0x00, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPUAndMemory()
+			cpu.PC = 0x0200
+			cpu.Memory[0x0200] = SBC_IMM
+			cpu.Memory[0x0201] = tt.operand
+			cpu.A = tt.accumulator
+			cpu.P = FlagD
+			if tt.carryIn {
+				cpu.P |= FlagC
+			}
+
+			cpu.Step()
+
+			assert.Equal(t, tt.expected, cpu.A, "incorrect BCD result")
+			assert.Equal(t, tt.expectC, cpu.P&FlagC != 0, "incorrect carry flag")
+		})
+	}
+}
+
+// TestSBCMatchesComplementAdd checks the binary path against ADC of the
+// one's complement for every operand pair and carry.
+func TestSBCMatchesComplementAdd(t *testing.T) {
+	sub := NewCPUAndMemory()
+	add := NewCPUAndMemory()
+	for a := 0; a < 256; a++ {
+		for v := 0; v < 256; v++ {
+			for _, carry := range []uint8{0, FlagC} {
+				sub.A, sub.P = uint8(a), carry
+				add.A, add.P = uint8(a), carry
+				sub.sbc(uint8(v))
+				add.adc(^uint8(v))
+				if sub.A != add.A || sub.P != add.P {
+					t.Fatalf("$%02X - $%02X (C=%d): got A=$%02X P=$%02X, want A=$%02X P=$%02X",
+						a, v, carry, sub.A, sub.P, add.A, add.P)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cpu
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CPUSnapshot is the saved register state of a CPU
+type CPUSnapshot struct {
+	A, X, Y uint8
+	PC      uint16
+	SP      uint8
+	P       uint8
+	Cycles  uint64
+}
+
+// snapshotMagic and snapshotVersion head the binary form of a CPUSnapshot
+const (
+	snapshotMagic   = "6502"
+	snapshotVersion = 1
+	snapshotSize    = len(snapshotMagic) + 1 + 7 + 8
+)
+
+// Snapshot captures the registers and cycle counter
+func (c *CPU) Snapshot() CPUSnapshot {
+	return CPUSnapshot{
+		A:      c.A,
+		X:      c.X,
+		Y:      c.Y,
+		PC:     c.PC,
+		SP:     c.SP,
+		P:      c.P,
+		Cycles: c.Cycles,
+	}
+}
+
+// Restore loads registers and the cycle counter from s. Memory is not part
+// of the snapshot.
+func (c *CPU) Restore(s CPUSnapshot) {
+	c.A = s.A
+	c.X = s.X
+	c.Y = s.Y
+	c.PC = s.PC
+	c.SP = s.SP
+	c.P = s.P
+	c.Cycles = s.Cycles
+}
+
+// MarshalBinary encodes the snapshot behind a magic and version header
+func (s CPUSnapshot) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, snapshotSize)
+	data = append(data, snapshotMagic...)
+	data = append(data, snapshotVersion, s.A, s.X, s.Y, s.SP, s.P)
+	data = binary.LittleEndian.AppendUint16(data, s.PC)
+	data = binary.LittleEndian.AppendUint64(data, s.Cycles)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a snapshot written by MarshalBinary
+func (s *CPUSnapshot) UnmarshalBinary(data []byte) error {
+	if len(data) < len(snapshotMagic)+1 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("not a CPU snapshot")
+	}
+	if version := data[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("unsupported CPU snapshot version %d", version)
+	}
+	if len(data) != snapshotSize {
+		return fmt.Errorf("CPU snapshot is %d bytes, expected %d", len(data), snapshotSize)
+	}
+	regs := data[len(snapshotMagic)+1:]
+	s.A, s.X, s.Y, s.SP, s.P = regs[0], regs[1], regs[2], regs[3], regs[4]
+	s.PC = binary.LittleEndian.Uint16(regs[5:])
+	s.Cycles = binary.LittleEndian.Uint64(regs[7:])
+	return nil
+}
@@ -0,0 +1,52 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	c := NewCPUAndMemory()
+	// Register-only loop so re-running from a snapshot is deterministic
+	copy(c.Memory[0x1000:], []uint8{
+		INX,
+		DEY,
+		ADC_IMM, 0x03,
+		BNE, 0xFA,
+		JMP_ABS, 0x00, 0x10,
+	})
+	c.PC = 0x1000
+
+	for i := 0; i < 37; i++ {
+		c.Step()
+	}
+	data, err := c.Snapshot().MarshalBinary()
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		c.Step()
+	}
+	first := c.Snapshot()
+
+	var saved CPUSnapshot
+	assert.NoError(t, saved.UnmarshalBinary(data))
+	c.Restore(saved)
+	assert.Equal(t, saved, c.Snapshot())
+
+	for i := 0; i < 100; i++ {
+		c.Step()
+	}
+	assert.Equal(t, first, c.Snapshot())
+	assert.NotZero(t, first.Cycles)
+}
+
+func TestSnapshotUnmarshalErrors(t *testing.T) {
+	data, _ := CPUSnapshot{PC: 0x1234}.MarshalBinary()
+
+	var s CPUSnapshot
+	assert.Error(t, s.UnmarshalBinary([]byte("nope")))
+	assert.Error(t, s.UnmarshalBinary(data[:len(data)-1]))
+
+	data[len(snapshotMagic)] = 99
+	assert.Error(t, s.UnmarshalBinary(data))
+}
@@ -0,0 +1,69 @@
+package cpu
+
+// Access is one memory access seen by a TracingBus
+type Access struct {
+	Addr  uint16
+	Value uint8
+	Write bool
+	PC    uint16 // CPU PC at the time, past any operand bytes already fetched
+}
+
+// TracingBus wraps a MemoryBus and remembers the most recent accesses in a
+// fixed-size ring buffer for post-mortem dumps.
+type TracingBus struct {
+	Bus MemoryBus
+	CPU *CPU // Supplies the PC recorded with each access; may be nil
+
+	history []Access
+	next    int
+	full    bool
+}
+
+// NewTracingBus wraps bus, keeping the last size accesses
+func NewTracingBus(bus MemoryBus, size int) *TracingBus {
+	return &TracingBus{
+		Bus:     bus,
+		history: make([]Access, size),
+	}
+}
+
+func (t *TracingBus) Read(address uint16) uint8 {
+	value := t.Bus.Read(address)
+	t.record(Access{Addr: address, Value: value})
+	return value
+}
+
+// Peek reads address without recording an access
+func (t *TracingBus) Peek(address uint16) uint8 {
+	return Peek(t.Bus, address)
+}
+
+func (t *TracingBus) Write(address uint16, value uint8) {
+	t.Bus.Write(address, value)
+	t.record(Access{Addr: address, Value: value, Write: true})
+}
+
+func (t *TracingBus) record(access Access) {
+	if len(t.history) == 0 {
+		return
+	}
+	if t.CPU != nil {
+		access.PC = t.CPU.PC
+	}
+	t.history[t.next] = access
+	t.next++
+	if t.next == len(t.history) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// History returns the recorded accesses, oldest first
+func (t *TracingBus) History() []Access {
+	if !t.full {
+		return append([]Access(nil), t.history[:t.next]...)
+	}
+	history := make([]Access, 0, len(t.history))
+	history = append(history, t.history[t.next:]...)
+	return append(history, t.history[:t.next]...)
+}
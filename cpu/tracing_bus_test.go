@@ -0,0 +1,42 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTracingBusHistory(t *testing.T) {
+	mem := &spyBus{}
+	bus := NewTracingBus(mem, 3)
+
+	bus.Write(0x0200, 0x11)
+	assert.Equal(t, []Access{{Addr: 0x0200, Value: 0x11, Write: true}}, bus.History())
+
+	bus.Read(0x0200)
+	bus.Write(0x0201, 0x22)
+	bus.Read(0x0201)
+
+	// Oldest access dropped, the rest in order
+	assert.Equal(t, []Access{
+		{Addr: 0x0200, Value: 0x11},
+		{Addr: 0x0201, Value: 0x22, Write: true},
+		{Addr: 0x0201, Value: 0x22},
+	}, bus.History())
+	assert.Equal(t, uint8(0x22), mem.Memory[0x0201], "writes reach the wrapped bus")
+}
+
+func TestTracingBusRecordsPC(t *testing.T) {
+	mem := &spyBus{}
+	copy(mem.Memory[0x1000:], []uint8{LDA_IMM, 0x42, STA_ABS, 0x00, 0x03})
+	bus := NewTracingBus(mem, 16)
+	c := NewCPU(bus)
+	bus.CPU = c
+	c.PC = 0x1000
+
+	c.Step()
+	c.Step()
+
+	history := bus.History()
+	last := history[len(history)-1]
+	assert.Equal(t, Access{Addr: 0x0300, Value: 0x42, Write: true, PC: 0x1005}, last)
+}
@@ -0,0 +1,69 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWAIResumesOnIRQ(t *testing.T) {
+	c := newIRQTestCPU(WAI, LDA_IMM, 0x01)
+	c.Variant = CMOS65C02
+	c.P = 0x20 // I clear
+
+	assert.Equal(t, uint8(3), c.Step())
+	assert.True(t, c.Halted())
+
+	// Idles without fetching while the line is quiet
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, uint8(1), c.Step())
+		assert.Equal(t, uint16(0x1001), c.PC)
+	}
+
+	c.SetIRQ(true)
+	assert.Equal(t, uint8(7), c.Step())
+	assert.False(t, c.Halted())
+	assert.Equal(t, uint16(0x2000), c.PC)
+}
+
+func TestWAIWithInterruptsMasked(t *testing.T) {
+	c := newIRQTestCPU(WAI, LDA_IMM, 0x01)
+	c.Variant = CMOS65C02
+	c.P = 0x24 // I set
+
+	c.Step()
+	c.SetIRQ(true)
+	c.Step()
+
+	// Wakes up but continues after WAI instead of entering the handler
+	assert.False(t, c.Halted())
+	assert.Equal(t, uint16(0x1003), c.PC)
+	assert.Equal(t, uint8(0x01), c.A)
+}
+
+func TestSTPRequiresReset(t *testing.T) {
+	c := newIRQTestCPU(STP, LDA_IMM, 0x01)
+	c.Variant = CMOS65C02
+	c.Memory[0xFFFC] = 0x01 // reset to the LDA after STP
+	c.Memory[0xFFFD] = 0x10
+	c.P = 0x20
+
+	c.Step()
+	c.SetIRQ(true)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, uint8(1), c.Step())
+	}
+	assert.True(t, c.Halted())
+	assert.Equal(t, uint16(0x1001), c.PC, "IRQ does not wake STP")
+
+	c.SetIRQ(false)
+	c.Reset()
+	assert.False(t, c.Halted())
+	c.Step()
+	assert.Equal(t, uint16(0x1003), c.PC, "execution restarts from the reset vector")
+	assert.Equal(t, uint8(0x01), c.A)
+}
+
+func TestWAIRequiresCMOS(t *testing.T) {
+	c := newIRQTestCPU(WAI)
+	assert.Panics(t, func() { c.Step() })
+}
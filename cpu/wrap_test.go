@@ -0,0 +1,82 @@
+package cpu
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Indexed absolute addresses wrap at 16 bits: $FFFF,X with X=1 is $0000.
+func TestIndexedAddressWrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint8
+		setup  func(*CPUAndMemory)
+		check  func(*assert.Assertions, *CPUAndMemory)
+	}{
+		{
+			name:   "STA Absolute,X",
+			opcode: STA_ABX,
+			setup:  func(c *CPUAndMemory) { c.X = 1; c.A = 0x37 },
+			check: func(a *assert.Assertions, c *CPUAndMemory) {
+				a.Equal(uint8(0x37), c.Memory[0x0000])
+			},
+		},
+		{
+			name:   "INC Absolute,X",
+			opcode: INC_ABX,
+			setup:  func(c *CPUAndMemory) { c.X = 1; c.Memory[0x0000] = 0x41 },
+			check: func(a *assert.Assertions, c *CPUAndMemory) {
+				a.Equal(uint8(0x42), c.Memory[0x0000])
+			},
+		},
+		{
+			name:   "ASL Absolute,X",
+			opcode: ASL_ABX,
+			setup:  func(c *CPUAndMemory) { c.X = 2; c.Memory[0x0001] = 0x21 },
+			check: func(a *assert.Assertions, c *CPUAndMemory) {
+				a.Equal(uint8(0x42), c.Memory[0x0001])
+			},
+		},
+		{
+			name:   "LDA Absolute,X",
+			opcode: LDA_ABX,
+			setup:  func(c *CPUAndMemory) { c.X = 1; c.Memory[0x0000] = 0x55 },
+			check: func(a *assert.Assertions, c *CPUAndMemory) {
+				a.Equal(uint8(0x55), c.A)
+			},
+		},
+		{
+			name:   "LDA Absolute,Y",
+			opcode: LDA_ABY,
+			setup:  func(c *CPUAndMemory) { c.Y = 0x10; c.Memory[0x000F] = 0x66 },
+			check: func(a *assert.Assertions, c *CPUAndMemory) {
+				a.Equal(uint8(0x66), c.A)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCPUAndMemory()
+			c.PC = 0x0200
+			copy(c.Memory[0x0200:], []uint8{tt.opcode, 0xFF, 0xFF})
+			tt.setup(c)
+
+			assert.NotPanics(t, func() { c.Step() })
+			tt.check(assert.New(t), c)
+		})
+	}
+}
+
+func TestIndirectIndexedWrap(t *testing.T) {
+	c := NewCPUAndMemory()
+	c.PC = 0x0200
+	copy(c.Memory[0x0200:], []uint8{LDA_INY, 0x10})
+	c.Memory[0x0010] = 0xFF // pointer to $FFFF
+	c.Memory[0x0011] = 0xFF
+	c.Memory[0x0001] = 0x77
+	c.Y = 2
+
+	c.Step()
+	assert.Equal(t, uint8(0x77), c.A)
+}
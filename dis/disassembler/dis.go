@@ -3,7 +3,6 @@ package disassembler
 import (
 	"fmt"
 	"github.com/newhook/6502/cpu"
-	"strings"
 )
 
 const maxMemory = 0xffff
@@ -13,27 +12,72 @@ type Location struct {
 	Value        uint8
 	OperandBytes []byte
 	Inst         *Instruction
+	Symbol       string // Name shown in place of the operand address, if known
+	Comment      string // Trailing annotation, e.g. the address behind Symbol
 }
 
 func (l Location) instruction() string {
+	return l.format(nil)
+}
+
+// annotate names the operand address from symbols, keeping the raw address
+// as a comment
+func (l Location) annotate(symbols SymbolTable) Location {
+	if addr, ok := l.OperandAddress(); ok {
+		if name, found := symbols[addr]; found {
+			l.Symbol = name
+			if addr < 0x100 && l.Inst.Mode != Relative {
+				l.Comment = fmt.Sprintf("$%02X", addr)
+			} else {
+				l.Comment = fmt.Sprintf("$%04X", addr)
+			}
+		}
+	}
+	return l
+}
+
+// format renders the mnemonic and operand, replacing operand addresses found
+// in symbols with their names.
+func (l Location) format(symbols SymbolTable) string {
 	if l.Inst == nil {
 		return fmt.Sprintf("$%04X: db $%02X        ; Invalid opcode\n", l.PC, l.Value)
 	}
-	operand := l.Inst.Mode.FormatOperand(l.OperandBytes)
+	operand := l.Inst.Mode.FormatOperandAt(l.PC, l.OperandBytes)
 	if operand == "" {
 		return l.Inst.Name
 	}
 
-	// Special case for relative addressing - update target address based on PC
-	if l.Inst.Mode == Relative {
-		offset := int8(l.OperandBytes[0])
-		target := l.PC + 2 + uint16(offset)
-		return fmt.Sprintf("%s $%04X", l.Inst.Name, target)
+	if l.Symbol != "" {
+		return fmt.Sprintf("%s %s", l.Inst.Name, l.Inst.Mode.formatAddress(l.Symbol))
+	}
+	if addr, ok := l.OperandAddress(); ok {
+		if name, found := symbols[addr]; found {
+			return fmt.Sprintf("%s %s", l.Inst.Name, l.Inst.Mode.formatAddress(name))
+		}
 	}
 
 	return fmt.Sprintf("%s %s", l.Inst.Name, operand)
 }
 
+// OperandAddress returns the memory address the operand refers to, resolving
+// branch offsets against the instruction's PC. Immediate and implied
+// operands have no address.
+func (l Location) OperandAddress() (uint16, bool) {
+	if l.Inst == nil {
+		return 0, false
+	}
+	switch l.Inst.Mode {
+	case ZeroPage, ZeroPageX, ZeroPageY, IndirectX, IndirectY, ZeroPageIndirect:
+		return uint16(l.OperandBytes[0]), true
+	case Absolute, AbsoluteX, AbsoluteY, Indirect, AbsoluteIndexedIndirect:
+		return uint16(l.OperandBytes[1])<<8 | uint16(l.OperandBytes[0]), true
+	case Relative:
+		offset := int8(l.OperandBytes[0])
+		return l.PC + 2 + uint16(offset), true
+	}
+	return 0, false
+}
+
 func (l Location) Size() int {
 	if l.Inst == nil {
 		return 1
@@ -41,23 +85,32 @@ func (l Location) Size() int {
 	return 1 + l.Inst.Mode.GetOperandBytes()
 }
 
-func (l Location) String() string {
+// Bytes returns the raw opcode and operand bytes of the instruction
+func (l Location) Bytes() []byte {
+	return append([]byte{l.Value}, l.OperandBytes...)
+}
+
+// hexDump formats the opcode and operand bytes
+func (l Location) hexDump() string {
 	var operandCount int
 	if l.Inst != nil {
 		operandCount = l.Inst.Mode.GetOperandBytes()
 	}
 
-	// Format the hex dump
-	var hexDump string
 	if operandCount == 0 {
-		hexDump = fmt.Sprintf("%02X", l.Value)
+		return fmt.Sprintf("%02X", l.Value)
 	} else if operandCount == 1 {
-		hexDump = fmt.Sprintf("%02X %02X", l.Value, l.OperandBytes[0])
-	} else {
-		hexDump = fmt.Sprintf("%02X %02X %02X", l.Value, l.OperandBytes[0], l.OperandBytes[1])
+		return fmt.Sprintf("%02X %02X", l.Value, l.OperandBytes[0])
 	}
+	return fmt.Sprintf("%02X %02X %02X", l.Value, l.OperandBytes[0], l.OperandBytes[1])
+}
 
-	return fmt.Sprintf("$%04X: %-8s  %s", l.PC, hexDump, l.instruction())
+func (l Location) String() string {
+	line := fmt.Sprintf("$%04X: %-8s  %s", l.PC, l.hexDump(), l.instruction())
+	if l.Comment != "" {
+		line += "  ; " + l.Comment
+	}
+	return line
 }
 
 // Decode takes an opcode and returns the corresponding instruction
@@ -66,43 +119,62 @@ func Decode(opcode byte) (Instruction, bool) {
 	return instruction, exists
 }
 
-func DisassembleInstructions(memory cpu.MemoryBus) []Location {
-	pc := 0
-	endAddr := maxMemory
-
-	var rows []Location
-	for pc < endAddr {
-		loc := disassembleLocation(memory, pc)
-		rows = append(rows, loc)
-		pc += loc.Size()
-	}
+// DisassembleInstructions decodes all of memory from $0000, naming operand
+// addresses found in symbols (which may be nil)
+func DisassembleInstructions(memory cpu.MemoryBus, symbols SymbolTable) []Location {
+	d := NewDisassembler(memory)
+	d.Symbols = symbols
+	return d.Window(0, maxMemory)
+}
 
-	return rows
+// DisassembleMemory disassembles a range of memory starting at the given
+// address, naming operand addresses found in symbols (which may be nil)
+func DisassembleMemory(memory cpu.MemoryBus, startAddr int, length int, symbols SymbolTable) string {
+	d := NewDisassembler(memory)
+	d.Symbols = symbols
+	return d.Memory(startAddr, length)
 }
 
-// DisassembleMemory disassembles a range of memory starting at the given address
-func DisassembleMemory(memory cpu.MemoryBus, startAddr int, length int) string {
-	var out strings.Builder
-	pc := startAddr
-	endAddr := startAddr + length
+// DisassembleSource disassembles a range of memory as assembler source
+// that reassembles to the same bytes, labelling branch and jump targets
+func DisassembleSource(memory cpu.MemoryBus, startAddr int, length int) string {
+	d := NewDisassembler(memory)
+	d.AutoLabel = true
+	return d.Source(startAddr, length)
+}
 
-	for pc < endAddr {
-		loc := disassembleLocation(memory, pc)
-		out.WriteString(loc.String())
-		out.WriteString("\n")
-		pc += loc.Size()
+// DisassembleAt formats the instruction at addr, read through bus, and
+// returns its size so callers can step to the next one. Branch targets are
+// resolved against addr. It lives here rather than on cpu.CPU because this
+// package already imports cpu.
+func DisassembleAt(bus cpu.MemoryBus, addr uint16) (text string, size int) {
+	l := NewDisassembler(bus).One(addr)
+	if l.Inst == nil {
+		return fmt.Sprintf(".byte $%02X", l.Value), 1
 	}
+	return l.instruction(), l.Size()
+}
 
-	return out.String()
+// DisassembleFromBus decodes count instructions starting at start, fetching
+// every opcode and operand through bus. When bus is a banked memory map this
+// yields the instruction stream the CPU actually sees (e.g. KERNAL ROM rather
+// than the RAM underneath it).
+func DisassembleFromBus(bus cpu.MemoryBus, start uint16, count int) []Location {
+	return NewDisassembler(bus).Window(start, count)
 }
 
-func disassembleLocation(memory cpu.MemoryBus, pc int) Location {
+func disassembleLocation(memory cpu.MemoryBus, pc int, cmos bool) Location {
 	// Get opcode
 	opcode := memory.Read(uint16(pc))
 	l := Location{PC: uint16(pc), Value: opcode}
 
 	// Decode instruction
 	inst, exists := instructionSet[opcode]
+	if cmos {
+		if cmosInst, ok := cmosInstructionSet[opcode]; ok {
+			inst, exists = cmosInst, true
+		}
+	}
 	if !exists {
 		// Handle invalid opcode
 		return l
@@ -133,5 +205,5 @@ func disassembleLocation(memory cpu.MemoryBus, pc int) Location {
 
 // DisassembleBytes is a convenience function for disassembling a slice of bytes
 func DisassembleBytes(bytes cpu.MemoryBus) string {
-	return DisassembleMemory(bytes, 0, maxMemory)
+	return DisassembleMemory(bytes, 0, maxMemory, nil)
 }
@@ -0,0 +1,309 @@
+package disassembler
+
+import (
+	"bytes"
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// bankedBus overlays a ROM image on RAM at $E000-$FFFF while romEnabled is
+// set, mimicking the c64 KERNAL banking.
+type bankedBus struct {
+	ram        [65536]uint8
+	rom        [0x2000]uint8
+	romEnabled bool
+}
+
+func (b *bankedBus) Read(address uint16) uint8 {
+	if b.romEnabled && address >= 0xE000 {
+		return b.rom[address-0xE000]
+	}
+	return b.ram[address]
+}
+
+func (b *bankedBus) Write(address uint16, value uint8) {
+	b.ram[address] = value
+}
+
+func TestDisassembleFromBus(t *testing.T) {
+	bus := &bankedBus{romEnabled: true}
+	// RAM under the ROM holds NOPs
+	for i := 0xE000; i < 0xE010; i++ {
+		bus.ram[i] = cpu.NOP
+	}
+	// ROM: SEI; LDX #$FF; JSR $FDA3
+	copy(bus.rom[:], []uint8{cpu.SEI, cpu.LDX_IMM, 0xFF, cpu.JSR_ABS, 0xA3, 0xFD})
+
+	locs := DisassembleFromBus(bus, 0xE000, 3)
+	if assert.Len(t, locs, 3) {
+		assert.Equal(t, uint16(0xE000), locs[0].PC)
+		assert.Equal(t, "SEI", locs[0].Inst.Name)
+		assert.Equal(t, uint16(0xE001), locs[1].PC)
+		assert.Equal(t, "LDX #$FF", locs[1].instruction())
+		assert.Equal(t, uint16(0xE003), locs[2].PC)
+		assert.Equal(t, "JSR $FDA3", locs[2].instruction())
+	}
+
+	// With the ROM banked out the RAM contents show through
+	bus.romEnabled = false
+	locs = DisassembleFromBus(bus, 0xE000, 2)
+	if assert.Len(t, locs, 2) {
+		assert.Equal(t, "NOP", locs[0].Inst.Name)
+		assert.Equal(t, uint16(0xE001), locs[1].PC)
+	}
+}
+
+type flatMemory [65536]uint8
+
+func (m *flatMemory) Read(address uint16) uint8         { return m[address] }
+func (m *flatMemory) Write(address uint16, value uint8) { m[address] = value }
+
+func TestDisassemblerOptions(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{
+		cpu.JSR_ABS, 0xD2, 0xFF, // JSR CHROUT
+		cpu.LDA_ZPX, 0xFB, // LDA PTR,X
+		cpu.BNE, 0xF9, // BNE START
+	})
+
+	d := NewDisassembler(mem)
+	d.Symbols = SymbolTable{0xFFD2: "CHROUT", 0x00FB: "PTR", 0x1000: "START"}
+
+	// Byte column on
+	assert.Equal(t, "$1000: 20 D2 FF  JSR CHROUT  ; $FFD2", d.Line(d.One(0x1000)))
+	assert.Equal(t,
+		"START:\n"+
+			"$1000: 20 D2 FF  JSR CHROUT  ; $FFD2\n"+
+			"$1003: B5 FB     LDA PTR,X  ; $FB\n"+
+			"$1005: D0 F9     BNE START  ; $1000\n",
+		d.Memory(0x1000, 7))
+
+	// Byte column off applies to every method
+	d.ShowBytes = false
+	window := d.Window(0x1003, 2)
+	if assert.Len(t, window, 2) {
+		assert.Equal(t, "$1003: LDA PTR,X  ; $FB", d.Line(window[0]))
+		assert.Equal(t, "$1005: BNE START  ; $1000", d.Line(window[1]))
+	}
+	assert.Equal(t,
+		"START:\n"+
+			"$1000: JSR CHROUT  ; $FFD2\n"+
+			"$1003: LDA PTR,X  ; $FB\n"+
+			"$1005: BNE START  ; $1000\n",
+		d.Memory(0x1000, 7))
+
+	assert.Equal(t, "\t.org $1000\nSTART:\n\tJSR CHROUT\n\tLDA PTR,X\n\tBNE START\n", d.Source(0x1000, 7))
+
+	// Package functions keep their original formatting
+	assert.Equal(t, "$1000: 20 D2 FF  JSR $FFD2\n", DisassembleMemory(mem, 0x1000, 3, nil))
+}
+
+func TestDisassemblerWriters(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{0xA9, 0x01, 0x8D, 0x00, 0x02, 0x02, 0x60})
+
+	d := NewDisassembler(mem)
+	d.Symbols[0x1000] = "START"
+
+	var memory bytes.Buffer
+	assert.NoError(t, d.WriteMemory(&memory, 0x1000, 7))
+	assert.Equal(t, d.Memory(0x1000, 7), memory.String())
+	assert.Equal(t, DisassembleMemory(mem, 0x1000, 7, nil), NewDisassembler(mem).Memory(0x1000, 7))
+
+	var source bytes.Buffer
+	assert.NoError(t, d.WriteSource(&source, 0x1000, 7))
+	assert.Equal(t, d.Source(0x1000, 7), source.String())
+}
+
+func TestDisassembleCMOS(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{
+		cpu.WAI,
+		cpu.STP,
+		cpu.BRA, 0xFC, // BRA $1000
+		cpu.STZ_ABX, 0x00, 0x04,
+		cpu.LDA_IZP, 0xFB,
+		cpu.JMP_IAX, 0x00, 0x20,
+		cpu.INC_ACC,
+		cpu.PHX,
+		cpu.TSB_ZP, 0x10,
+		cpu.BIT_IMM, 0x80,
+	})
+
+	d := NewDisassembler(mem)
+	assert.Nil(t, d.One(0x1000).Inst, "WAI is not an NMOS opcode")
+
+	d.CMOS = true
+	d.ShowBytes = false
+	assert.Equal(t, []string{
+		"$1000: WAI",
+		"$1001: STP",
+		"$1002: BRA $1000",
+		"$1004: STZ $0400,X",
+		"$1007: LDA ($FB)",
+		"$1009: JMP ($2000,X)",
+		"$100C: INC A",
+		"$100D: PHX",
+		"$100E: TSB $10",
+		"$1010: BIT #$80",
+	}, strings.Split(strings.TrimSuffix(d.Memory(0x1000, 0x12), "\n"), "\n"))
+
+	target, ok := d.One(0x1007).OperandAddress()
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0xFB), target)
+	target, ok = d.One(0x1009).OperandAddress()
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0x2000), target)
+}
+
+func TestDisassembleAt(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{
+		0xF0, 0xFE, // BEQ $1000
+		0x20, 0xD2, 0xFF, // JSR $FFD2
+		0x02, // not an NMOS opcode
+	})
+
+	tests := []struct {
+		addr uint16
+		text string
+		size int
+	}{
+		{0x1000, "BEQ $1000", 2},
+		{0x1002, "JSR $FFD2", 3},
+		{0x1005, ".byte $02", 1},
+	}
+	for _, tt := range tests {
+		text, size := DisassembleAt(mem, tt.addr)
+		assert.Equal(t, tt.text, text)
+		assert.Equal(t, tt.size, size)
+	}
+}
+
+func TestRelativeBranchTargets(t *testing.T) {
+	tests := []struct {
+		offset uint8
+		target string
+	}{
+		{0xFE, "$1000"},
+		{0x05, "$1007"},
+		{0x80, "$0F82"},
+		{0x7F, "$1081"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.target, Relative.FormatOperandAt(0x1000, []byte{tt.offset}))
+
+		mem := &flatMemory{}
+		mem[0x1000] = 0xF0 // BEQ
+		mem[0x1001] = tt.offset
+		assert.Contains(t, DisassembleMemory(mem, 0x1000, 2, nil), "BEQ "+tt.target)
+	}
+}
+
+func TestSymbolAnnotations(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{
+		cpu.JSR_ABS, 0xD2, 0xFF, // JSR CHROUT
+		cpu.JSR_ABS, 0x00, 0xC0, // JSR $C000, not in the table
+	})
+
+	locations := DisassembleInstructions(mem, KernalSymbols)
+	named := locations[0x1000]
+	assert.Equal(t, "CHROUT", named.Symbol)
+	assert.Equal(t, "$FFD2", named.Comment)
+	assert.Equal(t, "$1000: 20 D2 FF  JSR CHROUT  ; $FFD2", named.String())
+
+	unnamed := locations[0x1001] // zeros below $1000 decode as one-byte BRKs
+	assert.Empty(t, unnamed.Comment)
+	assert.Equal(t, "$1003: 20 00 C0  JSR $C000", unnamed.String())
+
+	assert.Equal(t,
+		"$1000: 20 D2 FF  JSR CHROUT  ; $FFD2\n"+
+			"$1003: 20 00 C0  JSR $C000\n",
+		DisassembleMemory(mem, 0x1000, 6, KernalSymbols))
+}
+
+func TestDataRegions(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{
+		0xA2, 0x00, // LDX #$00
+		0x6C, 0x05, 0x10, // JMP ($1005)
+		0x00, 0x20, // jump table: .word $2000
+		0x60, // RTS
+	})
+
+	// Without hints the table decodes as BRK and a JSR that swallows the RTS
+	d := NewDisassembler(mem)
+	d.ShowBytes = false
+	assert.Equal(t,
+		"$1000: LDX #$00\n"+
+			"$1002: JMP ($1005)\n"+
+			"$1005: BRK\n"+
+			"$1006: JSR $0060\n",
+		d.Memory(0x1000, 8))
+
+	d.Regions = []Region{{Start: 0x1005, End: 0x1006, Kind: WordRegion}}
+	assert.Equal(t,
+		"$1000: LDX #$00\n"+
+			"$1002: JMP ($1005)\n"+
+			"$1005: .word $2000\n"+
+			"$1007: RTS\n",
+		d.Memory(0x1000, 8))
+
+	assert.Equal(t,
+		"\t.org $1000\n"+
+			"\tLDX #$00\n"+
+			"\tJMP ($1005)\n"+
+			"\t.word $2000\n"+
+			"\tRTS\n",
+		d.Source(0x1000, 8))
+}
+
+func TestDataRegionResync(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0x1000:], []uint8{
+		0xEA,       // NOP
+		0x20, 0x01, // would be a JSR running into the data
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+		0xEA, // NOP
+	})
+
+	d := NewDisassembler(mem)
+	d.ShowBytes = false
+	d.Regions = []Region{{Start: 0x1003, End: 0x100B, Kind: ByteRegion}}
+	assert.Equal(t,
+		"$1000: NOP\n"+
+			"$1001: .byte $20, $01\n"+
+			"$1003: .byte $01, $02, $03, $04, $05, $06, $07, $08\n"+
+			"$100B: .byte $09\n"+
+			"$100C: NOP\n",
+		d.Memory(0x1000, 13))
+}
+
+func TestByteColumnAlignment(t *testing.T) {
+	mem := &flatMemory{}
+	copy(mem[0xF000:], []uint8{
+		0xEA,       // NOP
+		0xA9, 0xFF, // LDA #$FF
+		0x8D, 0x20, 0xD0, // STA $D020
+	})
+
+	d := NewDisassembler(mem)
+	lines := []string{
+		d.Line(d.One(0xF000)),
+		d.Line(d.One(0xF001)),
+		d.Line(d.One(0xF003)),
+	}
+	assert.Equal(t, []string{
+		"$F000: EA        NOP",
+		"$F001: A9 FF     LDA #$FF",
+		"$F003: 8D 20 D0  STA $D020",
+	}, lines)
+	assert.Equal(t, []byte{0x8D, 0x20, 0xD0}, d.One(0xF003).Bytes())
+
+	d.ShowBytes = false
+	assert.Equal(t, "$F001: LDA #$FF", d.Line(d.One(0xF001)))
+}
@@ -0,0 +1,237 @@
+package disassembler
+
+import (
+	"fmt"
+	"github.com/newhook/6502/cpu"
+	"io"
+	"strings"
+)
+
+// SymbolTable maps addresses to names used in place of raw operands
+type SymbolTable map[uint16]string
+
+// Disassembler decodes memory read through a bus according to its options
+type Disassembler struct {
+	Bus       cpu.MemoryBus
+	Symbols   SymbolTable // Names substituted for operand addresses and emitted as labels
+	ShowBytes bool        // Include the raw instruction bytes after the address
+	CMOS      bool        // Decode 65C02 opcodes
+	Regions   []Region    // Address ranges emitted as data by Memory and Source
+	AutoLabel bool        // Source labels branch, JMP and JSR targets within its range
+}
+
+// NewDisassembler creates a disassembler reading from bus, showing raw bytes
+// like the package-level functions do.
+func NewDisassembler(bus cpu.MemoryBus) *Disassembler {
+	return &Disassembler{
+		Bus:       bus,
+		Symbols:   SymbolTable{},
+		ShowBytes: true,
+	}
+}
+
+// One decodes the single instruction at addr
+func (d *Disassembler) One(addr uint16) Location {
+	return d.decode(int(addr))
+}
+
+// decode disassembles the instruction at pc and annotates it from Symbols
+func (d *Disassembler) decode(pc int) Location {
+	return disassembleLocation(d.Bus, pc, d.CMOS).annotate(d.Symbols)
+}
+
+// Window decodes count instructions starting at start, stopping early at the
+// top of memory.
+func (d *Disassembler) Window(start uint16, count int) []Location {
+	var rows []Location
+	pc := int(start)
+	for i := 0; i < count && pc <= maxMemory; i++ {
+		loc := d.decode(pc)
+		rows = append(rows, loc)
+		pc += loc.Size()
+	}
+	return rows
+}
+
+// Line formats a decoded location using the disassembler's options
+func (d *Disassembler) Line(l Location) string {
+	text := l.format(d.Symbols)
+	if l.Inst == nil {
+		return strings.TrimSuffix(text, "\n")
+	}
+	if l.Comment != "" {
+		text += "  ; " + l.Comment
+	}
+	if d.ShowBytes {
+		return fmt.Sprintf("$%04X: %-8s  %s", l.PC, l.hexDump(), text)
+	}
+	return fmt.Sprintf("$%04X: %s", l.PC, text)
+}
+
+// Memory disassembles length bytes starting at startAddr, one line per
+// instruction. Addresses with symbols are preceded by a label line, and
+// data Regions are emitted as .byte or .word lines.
+func (d *Disassembler) Memory(startAddr int, length int) string {
+	var out strings.Builder
+	d.WriteMemory(&out, startAddr, length)
+	return out.String()
+}
+
+// WriteMemory is Memory writing each line to w as it is decoded
+func (d *Disassembler) WriteMemory(w io.Writer, startAddr int, length int) error {
+	pc := startAddr
+	endAddr := startAddr + length
+
+	for pc < endAddr {
+		if name, ok := d.Symbols[uint16(pc)]; ok {
+			if _, err := fmt.Fprintf(w, "%s:\n", name); err != nil {
+				return err
+			}
+		}
+		if text, size := d.data(pc, endAddr); size > 0 {
+			if _, err := fmt.Fprintln(w, d.dataLine(pc, text)); err != nil {
+				return err
+			}
+			pc += size
+			continue
+		}
+		loc := d.decode(pc)
+		if _, err := fmt.Fprintln(w, d.Line(loc)); err != nil {
+			return err
+		}
+		pc += loc.Size()
+	}
+
+	return nil
+}
+
+// dataLine formats a data directive at pc in the columns Line uses
+func (d *Disassembler) dataLine(pc int, text string) string {
+	if d.ShowBytes {
+		return fmt.Sprintf("$%04X: %-8s  %s", pc, "", text)
+	}
+	return fmt.Sprintf("$%04X: %s", pc, text)
+}
+
+// Source disassembles length bytes starting at startAddr as assembler
+// source: a leading .org, no addresses or byte columns, labels on their own
+// lines, and undecodable bytes emitted with .byte. Absolute-mode operands
+// below $100 are emitted with .byte too, commented with the instruction, as
+// the assembler would shrink them to their zero-page form.
+func (d *Disassembler) Source(startAddr int, length int) string {
+	var out strings.Builder
+	d.WriteSource(&out, startAddr, length)
+	return out.String()
+}
+
+// WriteSource is Source writing each line to w as it is decoded
+func (d *Disassembler) WriteSource(w io.Writer, startAddr int, length int) error {
+	if d.AutoLabel {
+		saved := d.Symbols
+		d.Symbols = d.autoLabels(startAddr, startAddr+length)
+		defer func() { d.Symbols = saved }()
+	}
+	if _, err := fmt.Fprintf(w, "\t.org $%04X\n", startAddr); err != nil {
+		return err
+	}
+	pc := startAddr
+	endAddr := startAddr + length
+
+	for pc < endAddr {
+		if name, ok := d.Symbols[uint16(pc)]; ok {
+			if _, err := fmt.Fprintf(w, "%s:\n", name); err != nil {
+				return err
+			}
+		}
+		if text, size := d.data(pc, endAddr); size > 0 {
+			if _, err := fmt.Fprintf(w, "\t%s\n", text); err != nil {
+				return err
+			}
+			pc += size
+			continue
+		}
+		loc := d.decode(pc)
+		var err error
+		switch {
+		case loc.Inst == nil:
+			_, err = fmt.Fprintf(w, "\t.byte $%02X\n", loc.Value)
+		case d.reassemblesShorter(loc):
+			text, _ := d.byteDirective(pc, pc+loc.Size())
+			_, err = fmt.Fprintf(w, "\t%s ; %s\n", text, loc.format(d.Symbols))
+		default:
+			_, err = fmt.Fprintf(w, "\t%s\n", loc.format(d.Symbols))
+		}
+		if err != nil {
+			return err
+		}
+		pc += loc.Size()
+	}
+
+	return nil
+}
+
+// zeroPageForms maps each absolute mode to the zero-page mode an assembler
+// picks instead for operands below $100
+var zeroPageForms = map[AddressingMode]AddressingMode{
+	Absolute:  ZeroPage,
+	AbsoluteX: ZeroPageX,
+	AbsoluteY: ZeroPageY,
+}
+
+// reassemblesShorter reports whether l has an absolute operand below $100
+// and its mnemonic has the matching zero-page form, so reassembling its
+// source text would not give back the same bytes
+func (d *Disassembler) reassemblesShorter(l Location) bool {
+	zeroPage, ok := zeroPageForms[l.Inst.Mode]
+	if !ok || l.OperandBytes[1] != 0 {
+		return false
+	}
+	for _, set := range d.instructionSets() {
+		for _, inst := range set {
+			if inst.Name == l.Inst.Name && inst.Mode == zeroPage {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// instructionSets returns the opcode tables decoded with the current options
+func (d *Disassembler) instructionSets() []map[byte]Instruction {
+	if d.CMOS {
+		return []map[byte]Instruction{instructionSet, cmosInstructionSet}
+	}
+	return []map[byte]Instruction{instructionSet}
+}
+
+// autoLabels returns Symbols extended with an Lxxxx name for every branch,
+// JMP and JSR target that starts an instruction between startAddr and
+// endAddr. Targets elsewhere keep their plain addresses.
+func (d *Disassembler) autoLabels(startAddr int, endAddr int) SymbolTable {
+	starts := make(map[uint16]bool)
+	var targets []uint16
+	for pc := startAddr; pc < endAddr; {
+		if _, size := d.data(pc, endAddr); size > 0 {
+			pc += size
+			continue
+		}
+		loc := d.decode(pc)
+		starts[loc.PC] = true
+		if loc.Inst != nil && (loc.Inst.Mode == Relative || loc.Inst.OpCode == cpu.JMP_ABS || loc.Inst.OpCode == cpu.JSR_ABS) {
+			target, _ := loc.OperandAddress()
+			targets = append(targets, target)
+		}
+		pc += loc.Size()
+	}
+
+	symbols := make(SymbolTable, len(d.Symbols)+len(targets))
+	for addr, name := range d.Symbols {
+		symbols[addr] = name
+	}
+	for _, target := range targets {
+		if _, named := symbols[target]; !named && starts[target] {
+			symbols[target] = fmt.Sprintf("L%04X", target)
+		}
+	}
+	return symbols
+}
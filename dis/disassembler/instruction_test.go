@@ -0,0 +1,83 @@
+package disassembler
+
+import (
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInstructionCycles(t *testing.T) {
+	tests := []struct {
+		opcode   uint8
+		base     int
+		max      int
+		mode     AddressingMode
+		describe string
+	}{
+		{cpu.NOP, 2, 2, Implicit, "NOP"},
+		{cpu.ASL_ACC, 2, 2, Accumulator, "ASL A"},
+		{cpu.LDA_IMM, 2, 2, Immediate, "LDA #"},
+		{cpu.LDA_ZP, 3, 3, ZeroPage, "LDA zp"},
+		{cpu.LDA_ZPX, 4, 4, ZeroPageX, "LDA zp,X"},
+		{cpu.LDX_ZPY, 4, 4, ZeroPageY, "LDX zp,Y"},
+		{cpu.LDA_ABS, 4, 4, Absolute, "LDA abs"},
+		{cpu.LDA_ABX, 4, 5, AbsoluteX, "LDA abs,X"},
+		{cpu.STA_ABX, 5, 5, AbsoluteX, "STA abs,X"},
+		{cpu.INC_ABX, 7, 7, AbsoluteX, "INC abs,X"},
+		{cpu.LDA_ABY, 4, 5, AbsoluteY, "LDA abs,Y"},
+		{cpu.JMP_IND, 5, 5, Indirect, "JMP (abs)"},
+		{cpu.LDA_INX, 6, 6, IndirectX, "LDA (zp,X)"},
+		{cpu.LDA_INY, 5, 6, IndirectY, "LDA (zp),Y"},
+		{cpu.STA_INY, 6, 6, IndirectY, "STA (zp),Y"},
+		{cpu.BNE, 2, 4, Relative, "BNE"},
+		{cpu.JSR_ABS, 6, 6, Absolute, "JSR"},
+		{cpu.BRK, 7, 7, Implicit, "BRK"},
+		{cpu.BRA, 3, 4, Relative, "BRA"},
+		{cpu.STZ_ABX, 5, 5, AbsoluteX, "STZ abs,X"},
+		{cpu.BIT_ABX, 4, 5, AbsoluteX, "BIT abs,X"},
+		{cpu.LDA_IZP, 5, 5, ZeroPageIndirect, "LDA (zp)"},
+		{cpu.JMP_IAX, 6, 6, AbsoluteIndexedIndirect, "JMP (abs,X)"},
+		{cpu.JMP_IND, 5, 5, Indirect, "JMP (abs) on the NMOS 6502"},
+	}
+
+	for _, tt := range tests {
+		inst, ok := instructionSet[tt.opcode]
+		if !ok {
+			inst = cmosInstructionSet[tt.opcode]
+		}
+		assert.Equal(t, tt.mode, inst.Mode, tt.describe)
+		assert.Equal(t, tt.base, inst.BaseCycles(), tt.describe)
+		assert.Equal(t, tt.max, inst.MaxCycles(), tt.describe)
+	}
+}
+
+// TestInstructionCyclesMatchCPU runs every documented opcode that neither
+// branches nor crosses a page and checks the CPU takes the base cycles
+func TestInstructionCyclesMatchCPU(t *testing.T) {
+	for opcode, inst := range instructionSet {
+		if inst.Mode == Relative {
+			continue
+		}
+		c := cpu.NewCPUAndMemory()
+		c.Memory[0x1000] = opcode
+		c.Memory[0x1001] = 0x20
+		c.Memory[0x1002] = 0x30
+		c.PC = 0x1000
+		c.P |= cpu.FlagI
+		assert.Equal(t, inst.BaseCycles(), int(c.Step()), "%s %s ($%02X)", inst.Name, inst.Mode, opcode)
+	}
+}
+
+// TestCMOSInstructionCyclesMatchCPU does the same for the 65C02 opcodes
+func TestCMOSInstructionCyclesMatchCPU(t *testing.T) {
+	for opcode, inst := range cmosInstructionSet {
+		c := cpu.NewCPUAndMemory()
+		c.Variant = cpu.CMOS65C02
+		c.Memory[0x1000] = opcode
+		c.Memory[0x1001] = 0x20
+		c.Memory[0x1002] = 0x30
+		c.PC = 0x1000
+		c.P |= cpu.FlagI
+		assert.Equal(t, inst.BaseCycles(), int(c.Step()), "%s %s ($%02X)", inst.Name, inst.Mode, opcode)
+	}
+}
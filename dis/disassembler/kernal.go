@@ -0,0 +1,34 @@
+package disassembler
+
+// KernalSymbols names the C64 KERNAL jump table entries. Pass it (or a copy
+// extended with program symbols) to opt into annotated output.
+var KernalSymbols = SymbolTable{
+	0xFF81: "CINT",
+	0xFF84: "IOINIT",
+	0xFF87: "RAMTAS",
+	0xFF8A: "RESTOR",
+	0xFF8D: "VECTOR",
+	0xFF90: "SETMSG",
+	0xFF9F: "SCNKEY",
+	0xFFB7: "READST",
+	0xFFBA: "SETLFS",
+	0xFFBD: "SETNAM",
+	0xFFC0: "OPEN",
+	0xFFC3: "CLOSE",
+	0xFFC6: "CHKIN",
+	0xFFC9: "CHKOUT",
+	0xFFCC: "CLRCHN",
+	0xFFCF: "CHRIN",
+	0xFFD2: "CHROUT",
+	0xFFD5: "LOAD",
+	0xFFD8: "SAVE",
+	0xFFDB: "SETTIM",
+	0xFFDE: "RDTIM",
+	0xFFE1: "STOP",
+	0xFFE4: "GETIN",
+	0xFFE7: "CLALL",
+	0xFFEA: "UDTIM",
+	0xFFED: "SCREEN",
+	0xFFF0: "PLOT",
+	0xFFF3: "IOBASE",
+}
@@ -0,0 +1,79 @@
+package disassembler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegionKind says how the bytes of a Region are disassembled
+type RegionKind int
+
+const (
+	CodeRegion RegionKind = iota // Decoded as instructions
+	ByteRegion                   // Emitted as .byte, 8 per line
+	WordRegion                   // Emitted as little-endian .word, 4 per line
+)
+
+// Region marks the inclusive address range Start-End as code or data
+type Region struct {
+	Start, End uint16
+	Kind       RegionKind
+}
+
+// dataRegionAt returns the data region containing pc, if any
+func (d *Disassembler) dataRegionAt(pc int) (Region, bool) {
+	for _, r := range d.Regions {
+		if r.Kind != CodeRegion && pc >= int(r.Start) && pc <= int(r.End) {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// nextDataStart returns the start of the first data region after pc
+func (d *Disassembler) nextDataStart(pc int) (int, bool) {
+	next, found := 0, false
+	for _, r := range d.Regions {
+		if r.Kind != CodeRegion && int(r.Start) > pc && (!found || int(r.Start) < next) {
+			next, found = int(r.Start), true
+		}
+	}
+	return next, found
+}
+
+// data renders the bytes at pc as a data directive when pc lies in a data
+// region, or when the instruction there would run into one. It returns the
+// number of bytes covered, or 0 if pc should be decoded as an instruction.
+func (d *Disassembler) data(pc int, endAddr int) (string, int) {
+	if r, ok := d.dataRegionAt(pc); ok {
+		end := min(int(r.End)+1, endAddr)
+		if r.Kind == WordRegion && end-pc >= 2 {
+			n := min((end-pc)/2, 4)
+			words := make([]string, n)
+			for i := range words {
+				addr := uint16(pc + 2*i)
+				words[i] = fmt.Sprintf("$%02X%02X", d.Bus.Read(addr+1), d.Bus.Read(addr))
+			}
+			return ".word " + strings.Join(words, ", "), 2 * n
+		}
+		return d.byteDirective(pc, min(end, pc+8))
+	}
+
+	// Re-synchronize on the next region boundary rather than letting an
+	// instruction swallow its first bytes
+	if next, ok := d.nextDataStart(pc); ok {
+		if size := disassembleLocation(d.Bus, pc, d.CMOS).Size(); pc+size > next {
+			return d.byteDirective(pc, next)
+		}
+	}
+	return "", 0
+}
+
+// byteDirective renders the bytes from pc up to end as one .byte line
+func (d *Disassembler) byteDirective(pc int, end int) (string, int) {
+	values := make([]string, 0, end-pc)
+	for addr := pc; addr < end; addr++ {
+		values = append(values, fmt.Sprintf("$%02X", d.Bus.Read(uint16(addr))))
+	}
+	return ".byte " + strings.Join(values, ", "), end - pc
+}
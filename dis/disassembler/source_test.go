@@ -0,0 +1,97 @@
+package disassembler
+
+import (
+	"github.com/newhook/6502/as/assembler"
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSourceRoundTrip(t *testing.T) {
+	program := []uint8{
+		cpu.LDX_IMM, 0x00, // $C000 LDX #$00
+		cpu.LDA_INX, 0xFB, // $C002 LDA ($FB,X)
+		cpu.STA_INY, 0xFD, // $C004 STA ($FD),Y
+		cpu.LDA_ZPX, 0x10, // $C006 LDA $10,X
+		cpu.STA_ABY, 0x00, 0x04, // $C008 STA $0400,Y
+		cpu.ASL_ACC,             // $C00B ASL A
+		cpu.JSR_ABS, 0x18, 0xC0, // $C00C JSR $C018
+		cpu.INX,       // $C00F INX
+		cpu.BNE, 0xF0, // $C010 BNE $C002
+		cpu.BEQ, 0x03, // $C012 BEQ $C017
+		cpu.JMP_IND, 0xFC, 0xFF, // $C014 JMP ($FFFC)
+		cpu.RTS,                 // $C017 RTS
+		cpu.JSR_ABS, 0xD2, 0xFF, // $C018 JSR $FFD2
+		cpu.JMP_ABS, 0x17, 0xC0, // $C01B JMP $C017
+	}
+	mem := &flatMemory{}
+	copy(mem[0xC000:], program)
+
+	source := DisassembleSource(mem, 0xC000, len(program))
+	assert.Contains(t, source, "\t.org $C000\n")
+	assert.NotContains(t, source, "LFFD2", "no label outside the range")
+	for _, line := range []string{"LC002:\n", "\tBNE LC002\n", "\tJSR LC018\n", "\tJMP LC017\n", "\tLDA ($FB,X)\n", "\tJSR $FFD2\n"} {
+		assert.Contains(t, source, line)
+	}
+
+	asm := assembler.NewAssembler()
+	if assert.NoError(t, asm.Assemble(source), source) {
+		assert.Equal(t, program, asm.GetOutput(), source)
+	}
+}
+
+func TestSourceRoundTripAbsoluteZeroPageOperands(t *testing.T) {
+	program := []uint8{
+		cpu.LDA_ABS, 0x12, 0x00, // $C000 LDA $0012
+		cpu.LDA_ABX, 0x34, 0x00, // $C003 LDA $0034,X
+		cpu.ASL_ACC,   // $C006 ASL A
+		cpu.BNE, 0xF7, // $C007 BNE $C000
+		cpu.STA_ABY, 0x34, 0x00, // $C009 STA $0034,Y has no zero-page form
+		cpu.JSR_ABS, 0x12, 0x00, // $C00C JSR $0012
+		cpu.RTS, // $C00F RTS
+	}
+	mem := &flatMemory{}
+	copy(mem[0xC000:], program)
+
+	source := DisassembleSource(mem, 0xC000, len(program))
+	for _, line := range []string{
+		"\t.byte $AD, $12, $00 ; LDA $0012\n",
+		"\t.byte $BD, $34, $00 ; LDA $0034,X\n",
+		"\tBNE LC000\n",
+		"\tSTA $0034,Y\n",
+		"\tJSR $0012\n",
+	} {
+		assert.Contains(t, source, line)
+	}
+
+	asm := assembler.NewAssembler()
+	if assert.NoError(t, asm.Assemble(source), source) {
+		assert.Equal(t, program, asm.GetOutput(), source)
+	}
+}
+
+func TestSourceRoundTripCMOS(t *testing.T) {
+	program := []uint8{
+		cpu.STZ_ABS, 0x12, 0x00, // $C000 STZ $0012
+		cpu.LDA_IZP, 0xFB, // $C003 LDA ($FB)
+		cpu.INC_ACC,   // $C005 INC A
+		cpu.BRA, 0xF8, // $C006 BRA $C000
+		cpu.JMP_IAX, 0x00, 0xC0, // $C008 JMP ($C000,X)
+	}
+	mem := &flatMemory{}
+	copy(mem[0xC000:], program)
+
+	d := NewDisassembler(mem)
+	d.CMOS = true
+	d.AutoLabel = true
+	source := d.Source(0xC000, len(program))
+	for _, line := range []string{"\t.byte $9C, $12, $00 ; STZ $0012\n", "\tLDA ($FB)\n", "\tBRA LC000\n", "\tJMP (LC000,X)\n"} {
+		assert.Contains(t, source, line)
+	}
+
+	asm := assembler.NewAssembler()
+	asm.CMOS = true
+	if assert.NoError(t, asm.Assemble(source), source) {
+		assert.Equal(t, program, asm.GetOutput(), source)
+	}
+}
@@ -0,0 +1,81 @@
+package disassembler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// maxInstructionSize is the most bytes one instruction occupies
+const maxInstructionSize = 3
+
+// streamBus serves the bytes of a stream still buffered for decoding, the
+// first of them at base
+type streamBus struct {
+	base uint16
+	buf  []byte
+}
+
+func (b *streamBus) Read(addr uint16) uint8 {
+	if i := int(addr - b.base); i < len(b.buf) {
+		return b.buf[i]
+	}
+	return 0
+}
+
+func (b *streamBus) Write(addr uint16, value uint8) {}
+
+// DisassembleStream disassembles the bytes read from r as if loaded at
+// origin, writing the same lines as DisassembleMemory to w
+func DisassembleStream(r io.Reader, origin uint16, w io.Writer) error {
+	return NewDisassembler(nil).WriteStream(w, r, origin)
+}
+
+// WriteStream is WriteMemory over bytes read from r as if loaded at origin,
+// decoding as it reads so only one instruction is buffered at a time. An
+// instruction cut short by the end of the stream is written as .byte data.
+// Regions are not applied, and the Bus is only used during the call.
+func (d *Disassembler) WriteStream(w io.Writer, r io.Reader, origin uint16) error {
+	in := bufio.NewReader(r)
+	bus := &streamBus{base: origin}
+	saved := d.Bus
+	d.Bus = bus
+	defer func() { d.Bus = saved }()
+
+	for pc := int(origin); ; {
+		for len(bus.buf) < maxInstructionSize {
+			b, err := in.ReadByte()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			bus.buf = append(bus.buf, b)
+		}
+		if len(bus.buf) == 0 {
+			return nil
+		}
+		if pc > maxMemory {
+			return fmt.Errorf("stream from $%04X runs past the top of memory", origin)
+		}
+
+		if name, ok := d.Symbols[uint16(pc)]; ok {
+			if _, err := fmt.Fprintf(w, "%s:\n", name); err != nil {
+				return err
+			}
+		}
+		loc := d.decode(pc)
+		if loc.Size() > len(bus.buf) {
+			text, _ := d.byteDirective(pc, pc+len(bus.buf))
+			_, err := fmt.Fprintln(w, d.dataLine(pc, text))
+			return err
+		}
+		if _, err := fmt.Fprintln(w, d.Line(loc)); err != nil {
+			return err
+		}
+		bus.buf = bus.buf[loc.Size():]
+		bus.base += uint16(loc.Size())
+		pc += loc.Size()
+	}
+}
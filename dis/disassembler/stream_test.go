@@ -0,0 +1,57 @@
+package disassembler
+
+import (
+	"bytes"
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDisassembleStream(t *testing.T) {
+	program := []uint8{
+		cpu.LDA_IMM, 0x01, // LDA #$01
+		cpu.STA_ABS, 0x20, 0xD0, // STA $D020
+		0x02,          // Invalid opcode
+		cpu.BNE, 0xF8, // BNE $C000
+		cpu.JSR_ABS, 0xD2, 0xFF, // JSR $FFD2
+		cpu.RTS,
+	}
+	mem := &flatMemory{}
+	copy(mem[0xC000:], program)
+	want := DisassembleMemory(mem, 0xC000, len(program), nil)
+
+	var out bytes.Buffer
+	assert.NoError(t, DisassembleStream(bytes.NewReader(program), 0xC000, &out))
+	assert.Equal(t, want, out.String())
+
+	// One byte per Read exercises refilling mid-instruction
+	out.Reset()
+	assert.NoError(t, DisassembleStream(iotest.OneByteReader(bytes.NewReader(program)), 0xC000, &out))
+	assert.Equal(t, want, out.String(), "one byte at a time")
+
+	// Symbols and options apply as they do for WriteMemory
+	d := NewDisassembler(nil)
+	d.ShowBytes = false
+	d.Symbols[0xC000] = "START"
+	out.Reset()
+	assert.NoError(t, d.WriteStream(&out, bytes.NewReader(program[:5]), 0xC000))
+	assert.Equal(t, "START:\n$C000: LDA #$01\n$C002: STA $D020\n", out.String())
+	assert.Nil(t, d.Bus, "the bus is restored")
+}
+
+func TestDisassembleStreamTruncated(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, DisassembleStream(bytes.NewReader([]uint8{cpu.NOP, cpu.JMP_ABS, 0x00}), 0x1000, &out))
+	assert.Equal(t, "$1000: EA        NOP\n$1001:           .byte $4C, $00\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, DisassembleStream(bytes.NewReader(nil), 0x1000, &out))
+	assert.Empty(t, out.String())
+
+	// Nothing may be placed past $FFFF
+	assert.Error(t, DisassembleStream(bytes.NewReader([]uint8{cpu.NOP, cpu.NOP}), 0xFFFF, &out))
+
+	// Read errors are passed on
+	assert.ErrorIs(t, DisassembleStream(iotest.ErrReader(iotest.ErrTimeout), 0x1000, &out), iotest.ErrTimeout)
+}
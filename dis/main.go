@@ -10,19 +10,12 @@ import (
 	"strings"
 )
 
-type Memory [65536]uint8
-
-func (c *Memory) Read(address uint16) uint8 {
-	return c[address]
-}
-func (c *Memory) Write(address uint16, value uint8) {
-	c[address] = value
-}
-
 func main() {
 	// Command line flags
-	inputFile := flag.String("i", "", "Input binary file")
+	inputFile := flag.String("i", "", "Input binary file, or - for stdin")
 	startAddr := flag.String("a", "", "Start address")
+	kernal := flag.Bool("kernal", false, "Name C64 KERNAL entry points")
+	showBytes := flag.Bool("bytes", true, "Show raw instruction bytes")
 	flag.Parse()
 
 	addrStr := *startAddr
@@ -35,19 +28,40 @@ func main() {
 		return
 	}
 
-	// Create and initialize CPU
-	memory := &Memory{}
-	c := cpu.NewCPU(memory)
-	len, err := LoadAndSetupBinary(c, memory, *inputFile, int(startAddrInt))
+	// "-i -" streams stdin rather than loading a 64K image
+	if *inputFile == "-" {
+		d := disassembler.NewDisassembler(nil)
+		d.ShowBytes = *showBytes
+		if *kernal {
+			d.Symbols = disassembler.KernalSymbols
+		}
+		if err := d.WriteStream(os.Stdout, os.Stdin, uint16(startAddrInt)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		return
+	}
+
+	// Create and initialize CPU with a plain 64K of RAM
+	c := cpu.NewCPUAndMemory()
+	len, err := LoadAndSetupBinary(c, *inputFile, int(startAddrInt))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
 
-	fmt.Println(disassembler.DisassembleMemory(memory, int(startAddrInt), len))
+	d := disassembler.NewDisassembler(c)
+	d.ShowBytes = *showBytes
+	if *kernal {
+		d.Symbols = disassembler.KernalSymbols
+	}
+	if err := d.WriteMemory(os.Stdout, int(startAddrInt), len); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
 }
 
-func LoadAndSetupBinary(c *cpu.CPU, mem *Memory, filename string, startAddr int) (int, error) {
+func LoadAndSetupBinary(c *cpu.CPUAndMemory, filename string, startAddr int) (int, error) {
+	mem := &c.Memory
+
 	// Read the binary file
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/newhook/6502/cpu"
+	"github.com/newhook/6502/dis/disassembler"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAndDisassemble(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiny.bin")
+	program := []uint8{
+		0xA9, 0x01, // LDA #$01
+		0x8D, 0x20, 0xD0, // STA $D020
+		0x60, // RTS
+	}
+	assert.NoError(t, os.WriteFile(path, program, 0644))
+
+	c := cpu.NewCPUAndMemory()
+	n, err := LoadAndSetupBinary(c, path, 0xC000)
+	assert.NoError(t, err)
+	assert.Equal(t, len(program), n)
+	assert.Equal(t, uint16(0xC000), c.PC)
+	assert.Equal(t, program, c.Memory[0xC000:0xC000+n])
+
+	assert.Equal(t,
+		"$C000: A9 01     LDA #$01\n"+
+			"$C002: 8D 20 D0  STA $D020\n"+
+			"$C005: 60        RTS\n",
+		disassembler.DisassembleMemory(c, 0xC000, n, nil))
+
+	_, err = LoadAndSetupBinary(c, filepath.Join(t.TempDir(), "missing.bin"), 0xC000)
+	assert.Error(t, err)
+}
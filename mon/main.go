@@ -7,6 +7,7 @@ import (
 	"github.com/newhook/6502/cpu"
 	"github.com/newhook/6502/mon/monitor"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -53,30 +54,58 @@ func (c *Memory) Write(address uint16, value uint8) {
 
 func main() {
 	// Command line flags
-	inputFile := flag.String("i", "", "Input binary file")
+	inputFile := flag.String("i", "", "Input binary or .prg file")
 	startAddr := flag.String("a", "", "Start address")
 	flag.Parse()
 
-	addrStr := *startAddr
-	if strings.HasPrefix(addrStr, "$") {
-		addrStr = "0x" + addrStr[1:]
-	}
-	startAddrInt, err := strconv.ParseUint(addrStr, 0, 16)
-	if err != nil {
-		fmt.Printf("Error parsing start address: %v\n", err)
-		return
-	}
-
 	// Create and initialize CPU
 	memory := &Memory{}
 	c := cpu.NewCPU(memory)
-	_, err = LoadAndSetupBinary(c, memory, *inputFile, int(startAddrInt))
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return
+
+	// A .prg carries its own load address; -a then only overrides the PC
+	if strings.EqualFold(filepath.Ext(*inputFile), ".prg") {
+		data, err := os.ReadFile(*inputFile)
+		if err != nil {
+			fmt.Printf("Error: failed to read PRG file: %v\n", err)
+			return
+		}
+		start, err := cpu.LoadPRG(memory, data)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		c.PC = start
+		if *startAddr != "" {
+			addr, err := parseAddress(*startAddr)
+			if err != nil {
+				fmt.Printf("Error parsing start address: %v\n", err)
+				return
+			}
+			c.PC = addr
+		}
+	} else {
+		addr, err := parseAddress(*startAddr)
+		if err != nil {
+			fmt.Printf("Error parsing start address: %v\n", err)
+			return
+		}
+		_, err = LoadAndSetupBinary(c, memory, *inputFile, int(addr))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
 	}
 	p := tea.NewProgram(monitor.NewMonitor(c, c, memory))
 	if err := p.Start(); err != nil {
 		fmt.Printf("Error running program: %v", err)
 	}
 }
+
+// parseAddress reads a start address given as $hex, 0xhex or decimal
+func parseAddress(s string) (uint16, error) {
+	if strings.HasPrefix(s, "$") {
+		s = "0x" + s[1:]
+	}
+	addr, err := strconv.ParseUint(s, 0, 16)
+	return uint16(addr), err
+}
@@ -0,0 +1,227 @@
+package monitor
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"strconv"
+	"strings"
+)
+
+// commandKind selects what a command line entry does
+type commandKind int
+
+const (
+	cmdGo          commandKind = iota // Run until addr
+	cmdDisassemble                    // Show the disassembly at addr
+	cmdMemory                         // Show the memory view at addr
+	cmdRegister                       // Set register to value
+	cmdBreakpoint                     // Toggle a breakpoint at addr
+	cmdStep                           // Step count instructions
+	cmdFill                           // Fill addr..end with bytes[0]
+	cmdWrite                          // Write bytes starting at addr
+	cmdWatch                          // Add watch
+)
+
+// command is a parsed command line entry
+type command struct {
+	kind     commandKind
+	addr     uint16
+	end      uint16
+	bytes    []uint8
+	register string
+	value    uint16
+	count    int
+	watch    Watchpoint
+}
+
+// commandUsage lists the command line syntax, all values in hex
+const commandUsage = "g ADDR • d ADDR • m ADDR • r REG=VAL • bp ADDR • s [N] • f START END VAL • > ADDR VAL... • watch SPEC"
+
+// parseCommand reads a command line entry, all values in hex:
+//
+//	g ADDR             run until ADDR
+//	d ADDR             show the disassembly at ADDR
+//	m ADDR             show the memory view at ADDR
+//	r REG=VALUE        set register A, X, Y, SP, P or PC
+//	bp ADDR            toggle a breakpoint at ADDR
+//	s [N]              step N instructions (decimal, default 1)
+//	f START END VALUE  fill START..END inclusive with VALUE
+//	> ADDR VALUE...    write bytes starting at ADDR
+//	watch SPEC         add a watchpoint, as in the watch dialog
+func parseCommand(input string) (command, error) {
+	input = strings.TrimSpace(input)
+	if rest, ok := strings.CutPrefix(input, ">"); ok {
+		input = "> " + rest
+	}
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return command{}, fmt.Errorf("empty command")
+	}
+	name, args := strings.ToLower(fields[0]), fields[1:]
+
+	switch name {
+	case "g", "d", "m", "bp":
+		if len(args) != 1 {
+			return command{}, fmt.Errorf("usage: %s ADDR", name)
+		}
+		addr, err := parseHex(args[0], 16, "address")
+		if err != nil {
+			return command{}, err
+		}
+		kind := map[string]commandKind{"g": cmdGo, "d": cmdDisassemble, "m": cmdMemory, "bp": cmdBreakpoint}[name]
+		return command{kind: kind, addr: addr}, nil
+
+	case "r":
+		w, err := parseWatchpoint(strings.Join(args, ""))
+		if err != nil || w.Kind != WatchRegister {
+			return command{}, fmt.Errorf("usage: r REG=VALUE")
+		}
+		return command{kind: cmdRegister, register: w.Register, value: w.Value}, nil
+
+	case "s":
+		c := command{kind: cmdStep, count: 1}
+		if len(args) > 1 {
+			return command{}, fmt.Errorf("usage: s [N]")
+		}
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return command{}, fmt.Errorf("bad step count %q", args[0])
+			}
+			c.count = n
+		}
+		return c, nil
+
+	case "f":
+		if len(args) != 3 {
+			return command{}, fmt.Errorf("usage: f START END VALUE")
+		}
+		start, err := parseHex(args[0], 16, "start address")
+		if err != nil {
+			return command{}, err
+		}
+		end, err := parseHex(args[1], 16, "end address")
+		if err != nil {
+			return command{}, err
+		}
+		if end < start {
+			return command{}, fmt.Errorf("end address $%04X is before start $%04X", end, start)
+		}
+		value, err := parseHex(args[2], 8, "value")
+		if err != nil {
+			return command{}, err
+		}
+		return command{kind: cmdFill, addr: start, end: end, bytes: []uint8{uint8(value)}}, nil
+
+	case ">":
+		if len(args) < 2 {
+			return command{}, fmt.Errorf("usage: > ADDR VALUE...")
+		}
+		addr, err := parseHex(args[0], 16, "address")
+		if err != nil {
+			return command{}, err
+		}
+		c := command{kind: cmdWrite, addr: addr}
+		for _, arg := range args[1:] {
+			value, err := parseHex(arg, 8, "value")
+			if err != nil {
+				return command{}, err
+			}
+			c.bytes = append(c.bytes, uint8(value))
+		}
+		return c, nil
+
+	case "watch":
+		w, err := parseWatchpoint(strings.Join(args, " "))
+		if err != nil {
+			return command{}, err
+		}
+		return command{kind: cmdWatch, watch: w}, nil
+	}
+	return command{}, fmt.Errorf("unknown command %q", fields[0])
+}
+
+// parseHex reads a hex value of the given bit size, with an optional $
+func parseHex(s string, bits int, what string) (uint16, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(s, "$"), 16, bits)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s %q", what, s)
+	}
+	return uint16(value), nil
+}
+
+// runCommand carries out c, returning any command needed to resume
+// execution
+func (m *Monitor) runCommand(c command) tea.Cmd {
+	switch c.kind {
+	case cmdGo:
+		return m.goUntil(c.addr)
+	case cmdDisassemble:
+		m.showDisassembly(c.addr)
+	case cmdMemory:
+		m.memoryAddress = c.addr
+		m.captureMemoryState()
+	case cmdRegister:
+		m.setRegister(c.register, c.value)
+		m.status = fmt.Sprintf("%s = $%02X", c.register, c.value)
+	case cmdBreakpoint:
+		if m.breakpoints[c.addr] {
+			delete(m.breakpoints, c.addr)
+			m.status = fmt.Sprintf("breakpoint at $%04X removed", c.addr)
+		} else {
+			m.breakpoints[c.addr] = true
+			m.status = fmt.Sprintf("breakpoint at $%04X set", c.addr)
+		}
+	case cmdStep:
+		if !m.paused {
+			return nil
+		}
+		m.lastState = CPUState{A: m.cpu.A, X: m.cpu.X, Y: m.cpu.Y, PC: m.cpu.PC, SP: m.cpu.SP, P: m.cpu.P}
+		m.captureMemoryState()
+		for i := 0; i < c.count; i++ {
+			if hit := m.watch.step(m.cpu, m.stepper); hit != "" {
+				m.status = hit
+				break
+			}
+			if m.breakpoints[m.cpu.PC] {
+				break
+			}
+		}
+		m.relocate()
+	case cmdFill:
+		for addr := int(c.addr); addr <= int(c.end); addr++ {
+			m.mem.Write(uint16(addr), c.bytes[0])
+		}
+		m.refreshDisassembly()
+		m.status = fmt.Sprintf("filled $%04X-$%04X with $%02X", c.addr, c.end, c.bytes[0])
+	case cmdWrite:
+		for i, b := range c.bytes {
+			m.mem.Write(c.addr+uint16(i), b)
+		}
+		m.refreshDisassembly()
+		m.status = fmt.Sprintf("wrote %d bytes at $%04X", len(c.bytes), c.addr)
+	case cmdWatch:
+		m.watch.add(m.cpu, c.watch)
+		m.status = fmt.Sprintf("watching %s", c.watch)
+	}
+	return nil
+}
+
+// showDisassembly selects the instruction at addr, decoding from addr when
+// the precomputed list has no instruction starting there
+func (m *Monitor) showDisassembly(addr uint16) {
+	index := 0
+	for i, l := range m.locations {
+		if l.PC > addr {
+			break
+		}
+		index = i
+	}
+	if m.locations[index].PC == addr || index+1 >= len(m.locations) {
+		m.selectedLocation = index
+	} else {
+		m.selectedLocation = index + 1
+		m.realign(addr)
+		m.selectedLocation--
+	}
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		input string
+		want  command
+		err   bool
+	}{
+		{input: "g f000", want: command{kind: cmdGo, addr: 0xF000}},
+		{input: "d 0200", want: command{kind: cmdDisassemble, addr: 0x0200}},
+		{input: "M $C000", want: command{kind: cmdMemory, addr: 0xC000}},
+		{input: "r a=10", want: command{kind: cmdRegister, register: "A", value: 0x10}},
+		{input: "r pc = e000", want: command{kind: cmdRegister, register: "PC", value: 0xE000}},
+		{input: "bp f5a4", want: command{kind: cmdBreakpoint, addr: 0xF5A4}},
+		{input: "s", want: command{kind: cmdStep, count: 1}},
+		{input: "s 10", want: command{kind: cmdStep, count: 10}},
+		{input: "f 0400 07e7 20", want: command{kind: cmdFill, addr: 0x0400, end: 0x07E7, bytes: []uint8{0x20}}},
+		{input: "> c000 a9 01", want: command{kind: cmdWrite, addr: 0xC000, bytes: []uint8{0xA9, 0x01}}},
+		{input: ">c000 ea", want: command{kind: cmdWrite, addr: 0xC000, bytes: []uint8{0xEA}}},
+		{input: "watch w d020", want: command{kind: cmdWatch, watch: Watchpoint{Kind: WatchWrite, Addr: 0xD020}}},
+		{input: "", err: true},
+		{input: "zz 1000", err: true},
+		{input: "g", err: true},
+		{input: "g 10000", err: true},
+		{input: "r q=1", err: true},
+		{input: "r w 1000", err: true},
+		{input: "s 0", err: true},
+		{input: "f 2000 1000 00", err: true},
+		{input: "f 1000 2000 100", err: true},
+		{input: "> 1000", err: true},
+		{input: "watch", err: true},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCommand(tt.input)
+		if tt.err {
+			assert.Error(t, err, tt.input)
+			continue
+		}
+		if assert.NoError(t, err, tt.input) {
+			assert.Equal(t, tt.want, c, tt.input)
+		}
+	}
+}
+
+// enterCommand types line into the command bar and submits it
+func enterCommand(mon Monitor, line string) Monitor {
+	model, _ := mon.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+	mon = model.(Monitor)
+	for _, r := range line {
+		model, _ = mon.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		mon = model.(Monitor)
+	}
+	model, _ = mon.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return model.(Monitor)
+}
+
+func TestCommandLine(t *testing.T) {
+	m, c := newTestMonitor(
+		cpu.INX, // $1000
+		cpu.INX, // $1001
+		cpu.INX, // $1002
+	)
+
+	mon := enterCommand(*m, "f 2000 2003 ff")
+	assert.False(t, mon.showingCommand)
+	assert.Equal(t, []uint8{0xFF, 0xFF, 0xFF, 0xFF, 0x00}, c.Memory[0x2000:0x2005])
+
+	mon = enterCommand(mon, "> 2001 12 34")
+	assert.Equal(t, []uint8{0xFF, 0x12, 0x34, 0xFF}, c.Memory[0x2000:0x2004])
+
+	mon = enterCommand(mon, "r y=7f")
+	assert.Equal(t, uint8(0x7F), c.Y)
+
+	mon = enterCommand(mon, "s 2")
+	assert.Equal(t, uint16(0x1002), c.PC)
+	assert.Equal(t, uint8(0x02), c.X)
+
+	mon = enterCommand(mon, "bp 1002")
+	assert.True(t, mon.breakpoints[0x1002])
+
+	mon = enterCommand(mon, "m 2000")
+	assert.Equal(t, uint16(0x2000), mon.memoryAddress)
+
+	mon = enterCommand(mon, "d 1001")
+	assert.Equal(t, uint16(0x1001), mon.locations[mon.selectedLocation].PC)
+
+	// Errors are shown inline and keep the command bar open
+	mon = enterCommand(mon, "bogus")
+	assert.True(t, mon.showingCommand)
+	assert.Equal(t, `unknown command "bogus"`, mon.commandErr)
+	model, _ := mon.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, model.(Monitor).showingCommand)
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"strconv"
+)
+
+// Memory editor rows and columns, matching the memory pane layout
+const (
+	memoryColumns = 8
+	memoryRows    = 8
+)
+
+var editCursorStyle = lipgloss.NewStyle().Reverse(true)
+
+// startEditing enters the memory editor with the cursor on the first
+// visible byte
+func (m *Monitor) startEditing() {
+	m.editing = true
+	m.editAddr = m.memoryAddress
+	m.editNibble = 0
+	m.captureMemoryState()
+}
+
+// moveEditCursor moves the cursor by delta bytes, scrolling the memory view
+// to keep the cursor visible. A scrolled view starts on a row boundary, with
+// the cursor's row at its top or bottom.
+func (m *Monitor) moveEditCursor(delta int) {
+	addr := int(m.editAddr) + delta
+	if addr < 0 || addr > 0xFFFF {
+		return
+	}
+	m.editAddr = uint16(addr)
+	m.editNibble = 0
+
+	row := addr &^ (memoryColumns - 1)
+	if addr < int(m.memoryAddress) {
+		m.memoryAddress = uint16(row)
+	}
+	if addr >= int(m.memoryAddress)+memoryColumns*memoryRows {
+		m.memoryAddress = uint16(row - memoryColumns*(memoryRows-1))
+	}
+}
+
+// editInput applies a typed key to the byte under the cursor. In hex mode
+// each digit overwrites the nibble under the cursor, advancing to the next
+// byte after the low nibble; in ASCII mode a printable character replaces
+// the whole byte. Writes go straight through to memory.
+func (m *Monitor) editInput(key string) bool {
+	if len(key) != 1 {
+		return false
+	}
+
+	if m.editASCII {
+		ch := key[0]
+		if ch < 32 || ch > 126 {
+			return false
+		}
+		m.mem.Write(m.editAddr, ch)
+		m.moveEditCursor(1)
+		return true
+	}
+
+	digit, err := strconv.ParseUint(key, 16, 4)
+	if err != nil {
+		return false
+	}
+	value := m.mem.Read(m.editAddr)
+	if m.editNibble == 0 {
+		value = value&0x0F | uint8(digit)<<4
+		m.mem.Write(m.editAddr, value)
+		m.editNibble = 1
+		return true
+	}
+	value = value&0xF0 | uint8(digit)
+	m.mem.Write(m.editAddr, value)
+	m.moveEditCursor(1)
+	return true
+}
+
+// updateEditor handles keys while the memory editor is active
+func (m Monitor) updateEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.editing = false
+	case "tab":
+		m.editASCII = !m.editASCII
+		m.editNibble = 0
+	case "left":
+		m.moveEditCursor(-1)
+	case "right":
+		m.moveEditCursor(1)
+	case "up":
+		m.moveEditCursor(-memoryColumns)
+	case "down":
+		m.moveEditCursor(memoryColumns)
+	case "ctrl+c":
+		return m, tea.Quit
+	default:
+		if m.editInput(msg.String()) {
+			m.refreshDisassembly()
+		}
+	}
+	return m, nil
+}
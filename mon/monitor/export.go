@@ -0,0 +1,38 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseExportArgs parses "START END FILE" with hex addresses, e.g.
+// "C000 C0FF routine.asm". END is inclusive.
+func parseExportArgs(s string) (start, end uint16, path string, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return 0, 0, "", fmt.Errorf("usage: START END FILE")
+	}
+	startVal, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "$"), 16, 16)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid start address %q", fields[0])
+	}
+	endVal, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "$"), 16, 16)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid end address %q", fields[1])
+	}
+	if endVal < startVal {
+		return 0, 0, "", fmt.Errorf("end address $%04X is before start $%04X", endVal, startVal)
+	}
+	return uint16(startVal), uint16(endVal), fields[2], nil
+}
+
+// exportSource writes the routine between start and end (inclusive) to path
+// as assembler source that reassembles to the same bytes
+func (m *Monitor) exportSource(start, end uint16, path string) error {
+	d := m.newDisassembler()
+	d.AutoLabel = true
+	source := d.Source(int(start), int(end)-int(start)+1)
+	return os.WriteFile(path, []byte(source), 0644)
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/newhook/6502/cpu"
+	"github.com/newhook/6502/dis/disassembler"
+)
+
+// disassemblyLines is the height of the disassembly pane
+const disassemblyLines = 20
+
+// flowTarget returns where a JMP, JSR or branch transfers control. An
+// indirect JMP peeks at its vector in mem, reproducing the NMOS bug where a
+// vector at $xxFF takes its high byte from $xx00 unless variant is the
+// 65C02, which fixed it.
+func flowTarget(l disassembler.Location, mem cpu.MemoryBus, variant cpu.Variant) (uint16, bool) {
+	if l.Inst == nil {
+		return 0, false
+	}
+	switch l.Inst.OpCode {
+	case cpu.JMP_ABS, cpu.JSR_ABS:
+		return l.OperandAddress()
+	case cpu.JMP_IND:
+		vector, _ := l.OperandAddress()
+		next := vector&0xFF00 | (vector+1)&0x00FF
+		if variant == cpu.CMOS65C02 {
+			next = vector + 1
+		}
+		low := uint16(cpu.Peek(mem, vector))
+		high := uint16(cpu.Peek(mem, next))
+		return high<<8 | low, true
+	}
+	if l.Inst.Mode == disassembler.Relative {
+		return l.OperandAddress()
+	}
+	return 0, false
+}
+
+// follow moves the disassembly to the target of the selected instruction,
+// remembering where it came from
+func (m *Monitor) follow() {
+	from := m.locations[m.selectedLocation]
+	target, ok := flowTarget(from, m.mem, m.variant())
+	if !ok {
+		m.status = fmt.Sprintf("no jump or branch target at $%04X", from.PC)
+		return
+	}
+	m.followStack = append(m.followStack, from.PC)
+	m.showDisassembly(target)
+}
+
+// back returns to the instruction followed from most recently
+func (m *Monitor) back() {
+	if len(m.followStack) == 0 {
+		return
+	}
+	addr := m.followStack[len(m.followStack)-1]
+	m.followStack = m.followStack[:len(m.followStack)-1]
+	m.showDisassembly(addr)
+}
+
+// viewStart returns the first location shown in the disassembly pane,
+// keeping the selected line, and so the PC while stepping, centered
+func (m Monitor) viewStart() int {
+	start := m.selectedLocation - disassemblyLines/2
+	if start > len(m.locations)-disassemblyLines {
+		start = len(m.locations) - disassemblyLines
+	}
+	if start < 0 {
+		start = 0
+	}
+	return start
+}
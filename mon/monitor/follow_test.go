@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/newhook/6502/cpu"
+	"github.com/newhook/6502/dis/disassembler"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFlowTarget(t *testing.T) {
+	c := cpu.NewCPUAndMemory()
+	c.Memory[0x0300], c.Memory[0x0301] = 0x34, 0x12
+	c.Memory[0x30FF], c.Memory[0x3000], c.Memory[0x3100] = 0x80, 0x40, 0x50
+
+	tests := []struct {
+		name    string
+		bytes   []uint8
+		variant cpu.Variant
+		want    uint16
+		ok      bool
+	}{
+		{name: "JMP", bytes: []uint8{cpu.JMP_ABS, 0x00, 0xE0}, want: 0xE000, ok: true},
+		{name: "JSR", bytes: []uint8{cpu.JSR_ABS, 0xD2, 0xFF}, want: 0xFFD2, ok: true},
+		{name: "JMP indirect", bytes: []uint8{cpu.JMP_IND, 0x00, 0x03}, want: 0x1234, ok: true},
+		{name: "JMP indirect page wrap", bytes: []uint8{cpu.JMP_IND, 0xFF, 0x30}, want: 0x4080, ok: true},
+		{name: "JMP indirect on the 65C02", bytes: []uint8{cpu.JMP_IND, 0xFF, 0x30}, variant: cpu.CMOS65C02, want: 0x5080, ok: true},
+		{name: "branch forward", bytes: []uint8{cpu.BNE, 0x10}, want: 0x2012, ok: true},
+		{name: "branch back", bytes: []uint8{cpu.BEQ, 0xFE}, want: 0x2000, ok: true},
+		{name: "load", bytes: []uint8{cpu.LDA_ABS, 0x00, 0xC0}},
+		{name: "RTS", bytes: []uint8{cpu.RTS}},
+	}
+
+	bus := &peekingBus{countingBus{MemoryBus: c}}
+	for _, tt := range tests {
+		copy(c.Memory[0x2000:], tt.bytes)
+		l := disassembler.NewDisassembler(c).One(0x2000)
+		target, ok := flowTarget(l, bus, tt.variant)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.want, target, tt.name)
+	}
+	assert.Zero(t, bus.reads, "vectors are peeked at, not read")
+}
+
+// peekingBus is a countingBus whose peeks are not counted
+type peekingBus struct {
+	countingBus
+}
+
+func (b *peekingBus) Peek(address uint16) uint8 {
+	return cpu.Peek(b.MemoryBus, address)
+}
+
+func TestFollowAndBack(t *testing.T) {
+	m, _ := newTestMonitor(
+		cpu.JSR_ABS, 0x00, 0x11, // $1000 JSR $1100
+		cpu.NOP, // $1003
+	)
+	mon := *m
+
+	press := func(key tea.KeyType) {
+		model, _ := mon.Update(tea.KeyMsg{Type: key})
+		mon = model.(Monitor)
+	}
+
+	press(tea.KeyEnter)
+	assert.Equal(t, uint16(0x1100), mon.locations[mon.selectedLocation].PC)
+	press(tea.KeyBackspace)
+	assert.Equal(t, uint16(0x1000), mon.locations[mon.selectedLocation].PC)
+	press(tea.KeyBackspace)
+	assert.Equal(t, uint16(0x1000), mon.locations[mon.selectedLocation].PC, "empty back stack stays put")
+
+	// The selected line sits in the middle of the pane
+	assert.Equal(t, mon.selectedLocation-disassemblyLines/2, mon.viewStart())
+	mon.selectedLocation = 3
+	assert.Equal(t, 0, mon.viewStart())
+	mon.selectedLocation = len(mon.locations) - 1
+	assert.Equal(t, len(mon.locations)-disassemblyLines, mon.viewStart())
+}
@@ -46,12 +46,37 @@ type Monitor struct {
 	lastState  CPUState  // Previous CPU state for change detection
 	lastMemory [64]uint8 // Only track visible memory (8 rows * 8 bytes)
 
-	memoryAddress uint16 // Start address for memory view
-	activePane    string // "disasm", "memory"
-	gotoInput     textinput.Model
-	showingGoto   bool
+	memoryAddress  uint16 // Start address for memory view
+	activePane     string // "disasm", "memory", "cpu"
+	gotoInput      textinput.Model
+	showingGoto    bool
+	gotoRun        bool // Goto dialog targets a run-until address rather than the memory view
+	exportInput    textinput.Model
+	showingExport  bool
+	watchInput     textinput.Model
+	showingWatch   bool
+	commandInput   textinput.Model
+	showingCommand bool
+	commandErr     string // Error from the last command line entry, shown inline
+	status         string // Result of the last command, shown under the help line
 
 	breakpoints map[uint16]bool // Track breakpoint addresses
+	followStack []uint16        // Instructions followed from, most recent last
+	watch       *watcher        // Data watchpoints and register conditions
+	showCycles  bool            // Append base cycle counts to disassembly lines
+	hideBytes   bool            // Compact disassembly without the raw byte column
+
+	runUntil    uint16 // One-shot breakpoint address
+	hasRunUntil bool
+
+	editing    bool   // Memory editor active
+	editAddr   uint16 // Address under the editor cursor
+	editNibble int    // 0 for the high nibble, 1 for the low nibble
+	editASCII  bool   // Typed keys edit the ASCII column instead of hex
+
+	regEditing bool   // Register editor active
+	regField   int    // Index into registerFields under the editor cursor
+	regInput   string // Hex digits typed for the selected register
 }
 
 // Define some basic styles
@@ -116,21 +141,54 @@ func NewMonitor(stepper Stepper, cpu *cpu.CPU, mem cpu.MemoryBus) *Monitor {
 	ti.CharLimit = 4
 	ti.Width = 6
 
+	ei := textinput.New()
+	ei.Placeholder = "C000 C0FF routine.asm"
+	ei.Width = 40
+
+	wi := textinput.New()
+	wi.Placeholder = "w D020 01, r 00FB or A=10"
+	wi.Width = 30
+
+	ci := textinput.New()
+	ci.Prompt = ":"
+	ci.Placeholder = "g F000, m 0200, r A=10, bp F5A4"
+	ci.Width = 40
+
 	m := &Monitor{
 		stepper:       stepper,
 		mem:           mem,
 		cpu:           cpu,
 		paused:        true,
-		locations:     disassembler.DisassembleInstructions(mem),
 		memoryAddress: 0,
 		activePane:    "disasm",
 		gotoInput:     ti,
+		exportInput:   ei,
+		watchInput:    wi,
+		commandInput:  ci,
 		breakpoints:   make(map[uint16]bool),
+		watch:         &watcher{},
 	}
+	m.locations = m.newDisassembler().Window(0, 0xFFFF)
 	m.relocate()
 	return m
 }
 
+// newDisassembler returns a disassembler over the monitored memory that
+// decodes the opcodes of the CPU's variant
+func (m *Monitor) newDisassembler() *disassembler.Disassembler {
+	d := disassembler.NewDisassembler(m.mem)
+	d.CMOS = m.variant() == cpu.CMOS65C02
+	return d
+}
+
+// variant returns the member of the 6502 family being monitored
+func (m *Monitor) variant() cpu.Variant {
+	if m.cpu == nil {
+		return cpu.NMOS6502
+	}
+	return m.cpu.Variant
+}
+
 // Helper function to capture current memory view state
 func (m *Monitor) captureMemoryState() {
 	addr := m.memoryAddress
@@ -154,11 +212,14 @@ func (m Monitor) formatMemory() string {
 			value := m.mem.Read(addr + uint16(col))
 			lastValue := m.lastMemory[offset]
 
+			cell := fmt.Sprintf("%02X", value)
 			if value != lastValue {
-				result.WriteString(changedStyle.Render(fmt.Sprintf("%02X ", value)))
-			} else {
-				result.WriteString(fmt.Sprintf("%02X ", value))
+				cell = changedStyle.Render(cell)
 			}
+			if m.editing && !m.editASCII && m.editAddr == addr+uint16(col) {
+				cell = editCursorStyle.Render(cell)
+			}
+			result.WriteString(cell + " ")
 		}
 
 		// Add ASCII representation
@@ -168,19 +229,17 @@ func (m Monitor) formatMemory() string {
 			value := m.mem.Read(addr + uint16(col))
 			lastValue := m.lastMemory[offset]
 
+			cell := "."
 			if value >= 32 && value <= 126 {
-				if value != lastValue {
-					result.WriteString(changedStyle.Render(string(value)))
-				} else {
-					result.WriteString(string(value))
-				}
-			} else {
-				if value != lastValue {
-					result.WriteString(changedStyle.Render("."))
-				} else {
-					result.WriteString(".")
-				}
+				cell = string(value)
+			}
+			if value != lastValue {
+				cell = changedStyle.Render(cell)
+			}
+			if m.editing && m.editASCII && m.editAddr == addr+uint16(col) {
+				cell = editCursorStyle.Render(cell)
 			}
+			result.WriteString(cell)
 		}
 
 		result.WriteString("\n")
@@ -211,8 +270,9 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case stepTick:
 		// Check if we hit a breakpoint
-		if m.paused || m.breakpoints[m.cpu.PC] {
+		if m.paused || m.breakpoints[m.cpu.PC] || (m.hasRunUntil && m.runUntil == m.cpu.PC) {
 			m.paused = true
+			m.hasRunUntil = false
 			return m, nil
 		}
 
@@ -228,8 +288,14 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.captureMemoryState()
 
 		// Execute step
-		m.stepper.Step()
+		hit := m.watch.step(m.cpu, m.stepper)
 		m.relocate()
+		if hit != "" {
+			m.paused = true
+			m.hasRunUntil = false
+			m.status = hit
+			return m, nil
+		}
 
 		// Continue stepping
 		return m, doStep()
@@ -239,13 +305,22 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case tea.KeyMsg:
+		if m.editing {
+			return m.updateEditor(msg)
+		}
+		if m.regEditing {
+			return m.updateRegisterEditor(msg)
+		}
 		if m.showingGoto {
 			switch msg.Type {
 			case tea.KeyEnter:
+				m.showingGoto = false
 				if addr, err := strconv.ParseUint(m.gotoInput.Value(), 16, 16); err == nil {
+					if m.gotoRun {
+						return m, m.goUntil(uint16(addr))
+					}
 					m.memoryAddress = uint16(addr)
 				}
-				m.showingGoto = false
 				return m, nil
 			case tea.KeyEsc:
 				m.showingGoto = false
@@ -255,12 +330,95 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.gotoInput, cmd = m.gotoInput.Update(msg)
 			return m, cmd
 		}
+		if m.showingExport {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.showingExport = false
+				start, end, path, err := parseExportArgs(m.exportInput.Value())
+				if err == nil {
+					err = m.exportSource(start, end, path)
+				}
+				if err != nil {
+					m.status = fmt.Sprintf("export failed: %v", err)
+				} else {
+					m.status = fmt.Sprintf("exported $%04X-$%04X to %s", start, end, path)
+				}
+				return m, nil
+			case tea.KeyEsc:
+				m.showingExport = false
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.exportInput, cmd = m.exportInput.Update(msg)
+			return m, cmd
+		}
+
+		if m.showingWatch {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.showingWatch = false
+				if w, err := parseWatchpoint(m.watchInput.Value()); err != nil {
+					m.status = fmt.Sprintf("watch failed: %v", err)
+				} else {
+					m.watch.add(m.cpu, w)
+					m.status = fmt.Sprintf("watching %s", w)
+				}
+				return m, nil
+			case tea.KeyEsc:
+				m.showingWatch = false
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.watchInput, cmd = m.watchInput.Update(msg)
+			return m, cmd
+		}
+
+		if m.showingCommand {
+			switch msg.Type {
+			case tea.KeyEnter:
+				c, err := parseCommand(m.commandInput.Value())
+				if err != nil {
+					m.commandErr = err.Error()
+					return m, nil
+				}
+				m.showingCommand = false
+				return m, m.runCommand(c)
+			case tea.KeyEsc:
+				m.showingCommand = false
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.commandInput, cmd = m.commandInput.Update(msg)
+			return m, cmd
+		}
 
 		switch msg.String() {
-		case "g":
+		case ":":
+			m.showingCommand = true
+			m.commandErr = ""
+			m.commandInput.SetValue("")
+			m.commandInput.Focus()
+			return m, textinput.Blink
+		case "g", "G":
+			// g moves the memory view, G runs until the entered address
 			m.showingGoto = true
+			m.gotoRun = msg.String() == "G"
+			m.gotoInput.SetValue("")
 			m.gotoInput.Focus()
 			return m, textinput.Blink
+		case "x":
+			m.showingExport = true
+			m.exportInput.SetValue("")
+			m.exportInput.Focus()
+			return m, textinput.Blink
+		case "w":
+			m.showingWatch = true
+			m.watchInput.SetValue("")
+			m.watchInput.Focus()
+			return m, textinput.Blink
+		case "W":
+			m.watch.points = nil
+			m.status = "watchpoints cleared"
 		case "q", "ctrl+c":
 			return m, tea.Quit
 		case "s":
@@ -276,7 +434,9 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					P:  m.cpu.P,
 				}
 				m.captureMemoryState()
-				m.stepper.Step()
+				if hit := m.watch.step(m.cpu, m.stepper); hit != "" {
+					m.status = hit
+				}
 				m.relocate()
 			}
 		case "b":
@@ -288,8 +448,18 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.breakpoints[addr] = true
 			}
 
+		case "enter":
+			if m.activePane == "disasm" {
+				m.follow()
+			}
+
+		case "backspace":
+			if m.activePane == "disasm" {
+				m.back()
+			}
+
 		case "n":
-			if m.paused && len(m.breakpoints) > 0 {
+			if m.paused && (len(m.breakpoints) > 0 || len(m.watch.points) > 0) {
 				m.paused = false
 				return m, doStep()
 			}
@@ -297,19 +467,36 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "p":
 			m.paused = !m.paused
 
+		case "r":
+			m.refreshDisassembly()
+			m.status = "disassembly refreshed"
+
+		case "c":
+			m.showCycles = !m.showCycles
+
+		case "v":
+			m.hideBytes = !m.hideBytes
+
+		case "e":
+			if m.activePane == "memory" {
+				m.startEditing()
+			} else if m.activePane == "cpu" {
+				m.startRegisterEditing()
+			}
+
 		case "tab":
-			if m.activePane == "disasm" {
+			switch m.activePane {
+			case "disasm":
 				m.activePane = "memory"
-			} else {
+			case "memory":
+				m.activePane = "cpu"
+			default:
 				m.activePane = "disasm"
 			}
 
 		case "up":
 			if m.activePane == "disasm" {
-				m.selectedLocation--
-				if m.selectedLocation < 0 {
-					m.selectedLocation = 0
-				}
+				m.scrollUp(1)
 			} else {
 				if m.memoryAddress >= 8 {
 					m.memoryAddress -= 8
@@ -319,8 +506,8 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "down":
 			if m.activePane == "disasm" {
 				m.selectedLocation++
-				if m.selectedLocation > len(m.locations)-20 {
-					m.selectedLocation = len(m.locations) - 20
+				if m.selectedLocation >= len(m.locations) {
+					m.selectedLocation = len(m.locations) - 1
 				}
 			} else {
 				if m.memoryAddress <= 0xFFF8 {
@@ -331,10 +518,7 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "pgup":
 			if m.activePane == "disasm" {
-				m.selectedLocation -= 20
-				if m.selectedLocation < 0 {
-					m.selectedLocation = 0
-				}
+				m.scrollUp(disassemblyLines)
 			} else if m.activePane == "memory" {
 				// Move memory view up by 64 bytes (8 rows)
 				if m.memoryAddress >= 64 {
@@ -346,9 +530,9 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "pgdown":
 			if m.activePane == "disasm" {
-				m.selectedLocation += 20
-				if m.selectedLocation > len(m.locations)-20 {
-					m.selectedLocation = len(m.locations) - 20
+				m.selectedLocation += disassemblyLines
+				if m.selectedLocation >= len(m.locations) {
+					m.selectedLocation = len(m.locations) - 1
 				}
 			} else if m.activePane == "memory" {
 				// Move memory view down by 64 bytes (8 rows)
@@ -368,8 +552,24 @@ func (m Monitor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// goUntil resumes free-running with a one-shot breakpoint at addr. The
+// breakpoint is discarded once hit, or when execution stops for any other
+// reason.
+func (m *Monitor) goUntil(addr uint16) tea.Cmd {
+	if !m.paused {
+		return nil
+	}
+	m.runUntil = addr
+	m.hasRunUntil = true
+	m.paused = false
+	return doStep()
+}
+
 // Format register value with highlighting if changed
 func (m Monitor) formatReg8(name string, current, last uint8) string {
+	if cell, editing := m.registerCell(name); editing {
+		return cell
+	}
 	value := fmt.Sprintf("%s: $%02X", name, current)
 	if current != last {
 		return changedStyle.Render(value)
@@ -378,6 +578,9 @@ func (m Monitor) formatReg8(name string, current, last uint8) string {
 }
 
 func (m Monitor) formatReg16(name string, current, last uint16) string {
+	if cell, editing := m.registerCell(name); editing {
+		return cell
+	}
 	value := fmt.Sprintf("%s: $%04X", name, current)
 	if current != last {
 		return changedStyle.Render(value)
@@ -385,6 +588,20 @@ func (m Monitor) formatReg16(name string, current, last uint16) string {
 	return value
 }
 
+// registerCell renders register name under the register editor cursor,
+// showing any digits typed so far in place of its value
+func (m Monitor) registerCell(name string) (string, bool) {
+	if !m.regEditing || registerFields[m.regField] != name {
+		return "", false
+	}
+	digits := registerDigits(name)
+	value := m.regInput + strings.Repeat("_", digits-len(m.regInput))
+	if m.regInput == "" {
+		value = fmt.Sprintf("%0*X", digits, registerValue(m.cpu, name))
+	}
+	return editCursorStyle.Render(fmt.Sprintf("%s: $%s", name, value)), true
+}
+
 // Format CPU flags with highlighting for changes
 func (m Monitor) formatFlags() string {
 	flags := []struct {
@@ -405,6 +622,14 @@ func (m Monitor) formatFlags() string {
 		current := m.cpu.P&f.flag != 0
 		last := m.lastState.P&f.flag != 0
 
+		if m.regEditing && registerFields[m.regField] == f.name {
+			cell := "-"
+			if current {
+				cell = f.name
+			}
+			result.WriteString(editCursorStyle.Render(cell) + " ")
+			continue
+		}
 		if current {
 			if current != last {
 				result.WriteString(changedStyle.Render(f.name + " "))
@@ -422,10 +647,10 @@ func (m Monitor) formatFlags() string {
 func (m Monitor) disassemble() string {
 	var result strings.Builder
 
-	for i := 0; i < 20; i++ {
-		offset := m.selectedLocation + i
+	start := m.viewStart()
+	for offset := start; offset < start+disassemblyLines && offset < len(m.locations); offset++ {
 		l := m.locations[offset]
-		line := l.String()
+		line := m.formatLocation(l)
 		// Style the line based on whether it's the PC or selected line
 		if m.breakpoints[l.PC] {
 			if l.PC == m.cpu.PC {
@@ -446,6 +671,23 @@ func (m Monitor) disassemble() string {
 	return result.String()
 }
 
+// formatLocation renders a disassembled line, optionally followed by the
+// instruction's base cycle count. A "+1" marks modes that can take an extra
+// cycle on a page crossing.
+func (m Monitor) formatLocation(l disassembler.Location) string {
+	line := l.String()
+	if m.hideBytes {
+		line = (&disassembler.Disassembler{}).Line(l)
+	}
+	if !m.showCycles || l.Inst == nil {
+		return line
+	}
+	if l.Inst.PageCrossPenalty() {
+		return fmt.Sprintf("%s ; %d+1 cyc", line, l.Inst.BaseCycles())
+	}
+	return fmt.Sprintf("%s ; %d cyc", line, l.Inst.BaseCycles())
+}
+
 // Show stack contents
 func (m Monitor) formatStack() string {
 	var result strings.Builder
@@ -460,6 +702,9 @@ func (m Monitor) View() string {
 	// Calculate column widths
 	rightColumnWidth := 32
 	leftColumnWidth := 40 // Fixed width for disassembly
+	if m.showCycles {
+		leftColumnWidth += 12
+	}
 
 	// Update style widths
 	infoStyle = infoStyle.Width(rightColumnWidth)
@@ -474,12 +719,13 @@ func (m Monitor) View() string {
 
 	// Right column: CPU State with change highlighting
 	cpuState := infoStyle.Render(fmt.Sprintf(
-		"CPU State\n\n%s    %s    %s\n%s  %s\n\nFlags: %s\n",
+		"CPU State\n\n%s    %s    %s\n%s  %s    %s\n\nFlags: %s\n",
 		m.formatReg8("A", m.cpu.A, m.lastState.A),
 		m.formatReg8("X", m.cpu.X, m.lastState.X),
 		m.formatReg8("Y", m.cpu.Y, m.lastState.Y),
 		m.formatReg16("PC", m.cpu.PC, m.lastState.PC),
 		m.formatReg8("SP", m.cpu.SP, m.lastState.SP),
+		m.formatReg8("P", m.cpu.P, m.lastState.P),
 		m.formatFlags(),
 	))
 
@@ -503,16 +749,27 @@ func (m Monitor) View() string {
 
 	// Help section at the bottom
 	var help string
-	if !m.paused {
+	if m.editing {
+		help = titleStyle.Render(
+			"editing memory • ←→↑↓: move • 0-9a-f: enter nibble • tab: hex/ascii • esc: done",
+		)
+	} else if m.regEditing {
+		help = titleStyle.Render(
+			"editing registers • ←→↑↓: select • 0-9a-f: enter value • enter: set/toggle flag • esc: done",
+		)
+	} else if !m.paused {
 		help = titleStyle.Render(
 			"p: pause • q: quit",
 		)
 	} else {
 		help = titleStyle.Render(
-			"s: step • n: run to break • p: pause/resume • b: toggle break • " +
-				"↑↓: scroll • pgup/pgdn: page • tab: switch pane • g: goto • q: quit",
+			"s: step • n: run to break • p: pause/resume • b: toggle break • enter: follow • bksp: back • " +
+				"↑↓: scroll • pgup/pgdn: page • tab: switch pane • g: goto • G: go until • w: watch • W: clear watches • :: command • r: refresh • c: cycles • v: bytes • e: edit memory/registers • x: export source • q: quit",
 		)
 	}
+	if m.status != "" {
+		help = lipgloss.JoinVertical(lipgloss.Left, help, titleStyle.Render(m.status))
+	}
 
 	// Join columns horizontally with spacing
 	content := lipgloss.JoinHorizontal(
@@ -523,12 +780,16 @@ func (m Monitor) View() string {
 
 	// Add goto dialog if active
 	if m.showingGoto {
+		title := "Go to address:\n\n"
+		if m.gotoRun {
+			title = "Run until address:\n\n"
+		}
 		dialog := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			Padding(1).
 			Width(30).
 			Render(
-				"Go to address:\n\n" +
+				title +
 					m.gotoInput.View(),
 			)
 
@@ -540,6 +801,60 @@ func (m Monitor) View() string {
 		)
 	}
 
+	// Add export dialog if active
+	if m.showingExport {
+		dialog := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			Padding(1).
+			Width(50).
+			Render(
+				"Export source (start end file):\n\n" +
+					m.exportInput.View(),
+			)
+
+		return lipgloss.JoinVertical(
+			lipgloss.Center,
+			content,
+			help,
+			dialog,
+		)
+	}
+
+	// Add watch dialog if active
+	if m.showingWatch {
+		dialog := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			Padding(1).
+			Width(40).
+			Render(
+				"Watch (r|w ADDR [VALUE] or REG=VALUE):\n\n" +
+					m.watchInput.View(),
+			)
+
+		return lipgloss.JoinVertical(
+			lipgloss.Center,
+			content,
+			help,
+			dialog,
+		)
+	}
+
+	// Add the command line if active, with the last error underneath
+	if m.showingCommand {
+		line := m.commandInput.View()
+		if m.commandErr != "" {
+			line = lipgloss.JoinVertical(lipgloss.Left, line, changedStyle.Render(m.commandErr))
+		} else {
+			line = lipgloss.JoinVertical(lipgloss.Left, line, titleStyle.Render(commandUsage))
+		}
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			content,
+			help,
+			line,
+		)
+	}
+
 	// Join everything vertically
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
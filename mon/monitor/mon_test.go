@@ -0,0 +1,327 @@
+package monitor
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/newhook/6502/as/assembler"
+	"github.com/newhook/6502/cpu"
+	"github.com/newhook/6502/dis/disassembler"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestMonitor(program ...uint8) (*Monitor, *cpu.CPUAndMemory) {
+	c := cpu.NewCPUAndMemory()
+	copy(c.Memory[0x1000:], program)
+	c.PC = 0x1000
+	return NewMonitor(c, &c.CPU, c), c
+}
+
+func locationAt(t *testing.T, m *Monitor, pc uint16) disassembler.Location {
+	for _, l := range m.locations {
+		if l.PC == pc {
+			return l
+		}
+	}
+	t.Fatalf("no location at $%04X", pc)
+	return disassembler.Location{}
+}
+
+func TestDisassemblyDecodesCPUVariant(t *testing.T) {
+	m, _ := newTestMonitor(cpu.STZ_ABS, 0x00, 0xD0)
+	assert.Nil(t, locationAt(t, m, 0x1000).Inst, "STZ is not an NMOS opcode")
+
+	c := cpu.NewCPUAndMemory()
+	c.Variant = cpu.CMOS65C02
+	copy(c.Memory[0x1000:], []uint8{cpu.STZ_ABS, 0x00, 0xD0, cpu.BRA, 0xFB})
+	m = NewMonitor(c, &c.CPU, c)
+	line := (&disassembler.Disassembler{}).Line
+	assert.Equal(t, "$1000: STZ $D000", line(locationAt(t, m, 0x1000)))
+	assert.Equal(t, "$1003: BRA $1000", line(locationAt(t, m, 0x1003)))
+}
+
+func TestCycleColumn(t *testing.T) {
+	m, _ := newTestMonitor(
+		cpu.LDA_ABX, 0x34, 0x12, // LDA $1234,X
+		cpu.NOP,
+	)
+
+	lda := locationAt(t, m, 0x1000)
+	nop := locationAt(t, m, 0x1003)
+
+	// Off by default
+	assert.NotContains(t, m.formatLocation(lda), "cyc")
+
+	m.showCycles = true
+	assert.True(t, strings.HasSuffix(m.formatLocation(lda), "; 4+1 cyc"), m.formatLocation(lda))
+	assert.True(t, strings.HasSuffix(m.formatLocation(nop), "; 2 cyc"), m.formatLocation(nop))
+}
+
+// runUntilPaused feeds step ticks to the monitor until it pauses
+func runUntilPaused(t *testing.T, m Monitor) Monitor {
+	for i := 0; i < 1000; i++ {
+		if m.paused {
+			return m
+		}
+		model, _ := m.Update(stepTick{})
+		m = model.(Monitor)
+	}
+	t.Fatal("monitor never paused")
+	return m
+}
+
+func TestGoUntil(t *testing.T) {
+	m, c := newTestMonitor(
+		cpu.LDX_IMM, 0x03, // $1000 LDX #$03
+		cpu.DEX,       // $1002 DEX
+		cpu.BNE, 0xFD, // $1003 BNE $1002
+		cpu.LDA_IMM, 0x42, // $1005 LDA #$42
+		cpu.NOP, // $1007 NOP
+	)
+
+	assert.NotNil(t, m.goUntil(0x1005))
+	mon := runUntilPaused(t, *m)
+
+	assert.Equal(t, uint16(0x1005), c.PC)
+	assert.Equal(t, uint8(0x00), c.X, "loop should have completed")
+	assert.Equal(t, uint8(0x00), c.A, "target instruction should not have executed")
+	assert.False(t, mon.hasRunUntil, "one-shot breakpoint should be removed")
+	assert.Empty(t, mon.breakpoints)
+
+	// Resuming runs through the old target without stopping there again
+	mon.goUntil(0x1007)
+	mon = runUntilPaused(t, mon)
+	assert.Equal(t, uint16(0x1007), c.PC)
+	assert.Equal(t, uint8(0x42), c.A)
+}
+
+func TestMemoryEditor(t *testing.T) {
+	m, c := newTestMonitor()
+	m.memoryAddress = 0x2000
+	m.activePane = "memory"
+	m.startEditing()
+	assert.Equal(t, uint16(0x2000), m.editAddr)
+
+	// Two nibbles fill a byte and advance the cursor
+	assert.True(t, m.editInput("a"))
+	assert.Equal(t, uint8(0xA0), c.Memory[0x2000], "high nibble writes through immediately")
+	assert.True(t, m.editInput("9"))
+	assert.Equal(t, uint8(0xA9), c.Memory[0x2000])
+	assert.Equal(t, uint16(0x2001), m.editAddr)
+
+	// Non-hex keys are ignored
+	assert.False(t, m.editInput("z"))
+	assert.Equal(t, uint8(0x00), c.Memory[0x2001])
+
+	// Down moves a row, left moves a byte; moving resets the nibble
+	m.editInput("F")
+	m.moveEditCursor(memoryColumns)
+	m.moveEditCursor(-1)
+	assert.Equal(t, uint16(0x2008), m.editAddr)
+	m.editInput("1")
+	m.editInput("2")
+	assert.Equal(t, uint8(0xF0), c.Memory[0x2001])
+	assert.Equal(t, uint8(0x12), c.Memory[0x2008])
+
+	// ASCII entry replaces whole bytes
+	m.editASCII = true
+	m.editInput("H")
+	m.editInput("i")
+	assert.Equal(t, []uint8{'H', 'i'}, c.Memory[0x2009:0x200B])
+	assert.Equal(t, uint16(0x200B), m.editAddr)
+
+	// Moving past the bottom row scrolls the view
+	m.moveEditCursor(memoryColumns * memoryRows)
+	assert.Equal(t, uint16(0x204B), m.editAddr)
+	assert.Equal(t, uint16(0x2010), m.memoryAddress)
+	m.moveEditCursor(-memoryColumns * memoryRows)
+	assert.Equal(t, uint16(0x2008), m.memoryAddress)
+}
+
+func TestMemoryEditorUnalignedView(t *testing.T) {
+	m, _ := newTestMonitor()
+	m.memoryAddress = 0x0003
+	m.activePane = "memory"
+	m.startEditing()
+
+	m.moveEditCursor(-1)
+	assert.Equal(t, uint16(0x0002), m.editAddr)
+	assert.Equal(t, uint16(0x0000), m.memoryAddress)
+	m.moveEditCursor(-memoryColumns)
+	assert.Equal(t, uint16(0x0002), m.editAddr, "no row above $0000")
+
+	m.memoryAddress = 0xFFB3
+	m.editAddr = 0xFFFE
+	m.moveEditCursor(1)
+	assert.Equal(t, uint16(0xFFFF), m.editAddr)
+	assert.Equal(t, uint16(0xFFC0), m.memoryAddress)
+	m.moveEditCursor(1)
+	assert.Equal(t, uint16(0xFFFF), m.editAddr, "no byte past $FFFF")
+}
+
+func TestMemoryEditorKeys(t *testing.T) {
+	m, c := newTestMonitor()
+	m.memoryAddress = 0x3000
+	mon := *m
+
+	press := func(keys ...string) {
+		for _, k := range keys {
+			var msg tea.KeyMsg
+			switch k {
+			case "tab":
+				msg = tea.KeyMsg{Type: tea.KeyTab}
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			case "right":
+				msg = tea.KeyMsg{Type: tea.KeyRight}
+			case "down":
+				msg = tea.KeyMsg{Type: tea.KeyDown}
+			default:
+				msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			}
+			model, _ := mon.Update(msg)
+			mon = model.(Monitor)
+		}
+	}
+
+	press("tab", "e") // Switch to the memory pane and start editing
+	assert.True(t, mon.editing)
+	press("d", "e", "right", "down", "tab", "A", "esc")
+	assert.False(t, mon.editing)
+	assert.Equal(t, uint8(0xDE), c.Memory[0x3000])
+	assert.Equal(t, uint8('A'), c.Memory[0x300A])
+}
+
+func TestExportSource(t *testing.T) {
+	routine := []uint8{
+		0xA2, 0x03, // LDX #$03
+		0xCA,       // loop: DEX
+		0xD0, 0xFD, // BNE loop
+		0x20, 0xD2, 0xFF, // JSR $FFD2
+		0x60, // RTS
+	}
+	m, _ := newTestMonitor(routine...)
+
+	start, end, path, err := parseExportArgs("1000 1008 " + filepath.Join(t.TempDir(), "routine.asm"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x1000), start)
+	assert.Equal(t, uint16(0x1008), end)
+	assert.NoError(t, m.exportSource(start, end, path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	asm := assembler.NewAssembler()
+	assert.NoError(t, asm.Assemble(string(data)))
+	assert.Equal(t, routine, asm.GetOutput())
+}
+
+func TestParseExportArgs(t *testing.T) {
+	tests := []string{
+		"",
+		"1000 routine.asm",
+		"XYZ 1008 routine.asm",
+		"1008 1000 routine.asm",
+	}
+	for _, args := range tests {
+		_, _, _, err := parseExportArgs(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestScrollUpRealigns(t *testing.T) {
+	// The monitor decodes memory while it is all BRKs, so every byte is a
+	// boundary; the program written afterwards invalidates that list.
+	m, c := newTestMonitor()
+	copy(c.Memory[0x1000:], []uint8{
+		0xA9, 0x01, // LDA #$01
+		0x20, 0xD2, 0xFF, // JSR $FFD2
+		0xA2, 0x02, // LDX #$02
+	})
+
+	m.selectedLocation = 0x1005
+	assert.Equal(t, uint16(0x1005), m.locations[m.selectedLocation].PC)
+
+	m.scrollUp(1)
+	assert.Equal(t, uint16(0x1002), m.locations[m.selectedLocation].PC)
+	assert.Equal(t, "JSR", m.locations[m.selectedLocation].Inst.Name)
+
+	m.scrollUp(1)
+	assert.Equal(t, uint16(0x1000), m.locations[m.selectedLocation].PC)
+	assert.Equal(t, "LDA", m.locations[m.selectedLocation].Inst.Name)
+
+	// The list still covers memory contiguously around the splice
+	for i := m.selectedLocation - 4; i < m.selectedLocation+4; i++ {
+		l := m.locations[i]
+		assert.Equal(t, l.PC+uint16(l.Size()), m.locations[i+1].PC)
+	}
+}
+
+// countingBus counts the reads made through it
+type countingBus struct {
+	cpu.MemoryBus
+	reads int
+}
+
+func (b *countingBus) Read(address uint16) uint8 {
+	b.reads++
+	return b.MemoryBus.Read(address)
+}
+
+func TestPreviousInstructionDecodesEachByteOnce(t *testing.T) {
+	// Every byte decodes as a 2-byte LDA #$A9, so each candidate has a
+	// full chain of alternatives behind it
+	program := make([]uint8, 0x40)
+	for i := range program {
+		program[i] = cpu.LDA_IMM
+	}
+	m, c := newTestMonitor(program...)
+	bus := &countingBus{MemoryBus: c}
+	m.mem = bus
+
+	assert.Equal(t, uint16(0x1030-2), m.previousInstruction(0x1030))
+	assert.LessOrEqual(t, bus.reads, 3*3*(maxBackScan+1), "at most one decode per byte in the window")
+}
+
+func TestRefreshDisassembly(t *testing.T) {
+	m, c := newTestMonitor()
+	copy(c.Memory[0x1000:], []uint8{
+		0xA9, 0x01, // LDA #$01
+		0x20, 0xD2, 0xFF, // JSR $FFD2
+		0xA2, 0x02, // LDX #$02
+	})
+	// Far outside the refreshed window, so it stays stale
+	c.Memory[0x8000] = 0xEA
+
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	mon := model.(Monitor)
+
+	assert.Equal(t, uint16(0x1000), mon.locations[mon.selectedLocation].PC, "selection is kept")
+	assert.Equal(t, "LDA", locationAt(t, &mon, 0x1000).Inst.Name)
+	assert.Equal(t, "JSR", locationAt(t, &mon, 0x1002).Inst.Name)
+	assert.Equal(t, "LDX", locationAt(t, &mon, 0x1005).Inst.Name)
+	assert.Equal(t, "BRK", locationAt(t, &mon, 0x8000).Inst.Name)
+
+	for i := 1; i < len(mon.locations); i++ {
+		prev := mon.locations[i-1]
+		if !assert.Equal(t, prev.PC+uint16(prev.Size()), mon.locations[i].PC) {
+			break
+		}
+	}
+
+	// Writes from the command line refresh straight away
+	mon = enterCommand(mon, "> 1007 4c 00 10")
+	assert.Equal(t, "JMP", locationAt(t, &mon, 0x1007).Inst.Name)
+}
+
+func TestByteColumnToggle(t *testing.T) {
+	m, _ := newTestMonitor(cpu.LDA_IMM, 0xFF)
+	lda := locationAt(t, m, 0x1000)
+
+	assert.Equal(t, "$1000: A9 FF     LDA #$FF", m.formatLocation(lda))
+
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")})
+	compact := model.(Monitor)
+	assert.Equal(t, "$1000: LDA #$FF", compact.formatLocation(lda))
+}
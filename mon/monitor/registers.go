@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/newhook/6502/cpu"
+	"strconv"
+	"strings"
+)
+
+// registerFields are the CPU pane entries the register editor visits, in
+// order. The single-letter flag names toggle a bit of P.
+var registerFields = []string{"A", "X", "Y", "PC", "SP", "P", "N", "V", "B", "D", "I", "Z", "C"}
+
+// flagBits maps flag names to their bit in P
+var flagBits = map[string]uint8{
+	"N": cpu.FlagN,
+	"V": cpu.FlagV,
+	"B": cpu.FlagB,
+	"D": cpu.FlagD,
+	"I": cpu.FlagI,
+	"Z": cpu.FlagZ,
+	"C": cpu.FlagC,
+}
+
+// registerDigits returns how many hex digits register holds
+func registerDigits(register string) int {
+	if register == "PC" {
+		return 4
+	}
+	return 2
+}
+
+// parseRegisterValue reads a hex value for register, with an optional $,
+// rejecting values too wide for it
+func parseRegisterValue(register, s string) (uint16, error) {
+	if _, isFlag := flagBits[register]; isFlag {
+		return 0, fmt.Errorf("flag %s is toggled, not set", register)
+	}
+	switch register {
+	case "A", "X", "Y", "PC", "SP", "P":
+	default:
+		return 0, fmt.Errorf("unknown register %q", register)
+	}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "$")
+	value, err := strconv.ParseUint(s, 16, registerDigits(register)*4)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s value %q", register, s)
+	}
+	return uint16(value), nil
+}
+
+// assignRegister stores value in the named register of c
+func assignRegister(c *cpu.CPU, register string, value uint16) {
+	switch register {
+	case "A":
+		c.A = uint8(value)
+	case "X":
+		c.X = uint8(value)
+	case "Y":
+		c.Y = uint8(value)
+	case "SP":
+		c.SP = uint8(value)
+	case "P":
+		c.P = uint8(value)
+	case "PC":
+		c.PC = value
+	}
+}
+
+// registerValue reads the named register of c
+func registerValue(c *cpu.CPU, register string) uint16 {
+	switch register {
+	case "A":
+		return uint16(c.A)
+	case "X":
+		return uint16(c.X)
+	case "Y":
+		return uint16(c.Y)
+	case "SP":
+		return uint16(c.SP)
+	case "P":
+		return uint16(c.P)
+	case "PC":
+		return c.PC
+	}
+	return 0
+}
+
+// toggleFlag flips the named flag in c's status register
+func toggleFlag(c *cpu.CPU, flag string) {
+	c.P ^= flagBits[flag]
+}
+
+// setRegister assigns value to the named register, moving the disassembly
+// to a new PC
+func (m *Monitor) setRegister(register string, value uint16) {
+	assignRegister(m.cpu, register, value)
+	if register == "PC" {
+		m.relocate()
+	}
+}
+
+// startRegisterEditing enters the register editor on the first register
+func (m *Monitor) startRegisterEditing() {
+	m.regEditing = true
+	m.regField = 0
+	m.regInput = ""
+}
+
+// commitRegister stores the typed value in the selected register. Flags
+// toggle instead.
+func (m *Monitor) commitRegister() {
+	field := registerFields[m.regField]
+	if _, isFlag := flagBits[field]; isFlag {
+		toggleFlag(m.cpu, field)
+		return
+	}
+	if m.regInput == "" {
+		return
+	}
+	value, err := parseRegisterValue(field, m.regInput)
+	m.regInput = ""
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.setRegister(field, value)
+}
+
+// updateRegisterEditor handles keys while the register editor is active
+func (m Monitor) updateRegisterEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key := msg.String(); key {
+	case "esc":
+		m.regEditing = false
+		m.regInput = ""
+	case "up", "left":
+		m.regInput = ""
+		m.regField = (m.regField + len(registerFields) - 1) % len(registerFields)
+	case "down", "right":
+		m.regInput = ""
+		m.regField = (m.regField + 1) % len(registerFields)
+	case "enter", " ":
+		m.commitRegister()
+	case "backspace":
+		if m.regInput != "" {
+			m.regInput = m.regInput[:len(m.regInput)-1]
+		}
+	case "ctrl+c":
+		return m, tea.Quit
+	default:
+		field := registerFields[m.regField]
+		if _, isFlag := flagBits[field]; isFlag || len(key) != 1 {
+			break
+		}
+		if _, err := strconv.ParseUint(key, 16, 4); err != nil {
+			break
+		}
+		if len(m.regInput) < registerDigits(field) {
+			m.regInput += strings.ToUpper(key)
+		}
+	}
+	return m, nil
+}
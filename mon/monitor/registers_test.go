@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseRegisterValue(t *testing.T) {
+	tests := []struct {
+		register string
+		input    string
+		want     uint16
+		err      bool
+	}{
+		{register: "A", input: "7f", want: 0x7F},
+		{register: "SP", input: "$FD", want: 0xFD},
+		{register: "P", input: "24", want: 0x24},
+		{register: "PC", input: "e000", want: 0xE000},
+		{register: "PC", input: "7", want: 0x0007},
+		{register: "X", input: "100", err: true},
+		{register: "Y", input: "zz", err: true},
+		{register: "Y", input: "", err: true},
+		{register: "C", input: "1", err: true},
+		{register: "Q", input: "1", err: true},
+	}
+
+	for _, tt := range tests {
+		value, err := parseRegisterValue(tt.register, tt.input)
+		if tt.err {
+			assert.Error(t, err, "%s=%s", tt.register, tt.input)
+			continue
+		}
+		if assert.NoError(t, err, "%s=%s", tt.register, tt.input) {
+			assert.Equal(t, tt.want, value, "%s=%s", tt.register, tt.input)
+		}
+	}
+}
+
+func TestAssignRegister(t *testing.T) {
+	c := cpu.NewCPU(nil)
+	for _, register := range []string{"A", "X", "Y", "SP", "P"} {
+		assignRegister(c, register, 0x5A)
+		assert.Equal(t, uint16(0x5A), registerValue(c, register), register)
+	}
+	assignRegister(c, "PC", 0xC000)
+	assert.Equal(t, uint16(0xC000), c.PC)
+
+	c.P = 0
+	toggleFlag(c, "C")
+	toggleFlag(c, "N")
+	assert.Equal(t, cpu.FlagC|cpu.FlagN, c.P)
+	toggleFlag(c, "C")
+	assert.Equal(t, cpu.FlagN, c.P)
+}
+
+func TestRegisterEditorKeys(t *testing.T) {
+	m, c := newTestMonitor(cpu.NOP, cpu.NOP, cpu.NOP)
+	c.Memory[0x2000] = cpu.INX
+	c.P = 0
+	mon := *m
+
+	press := func(keys ...string) {
+		for _, k := range keys {
+			var msg tea.KeyMsg
+			switch k {
+			case "tab":
+				msg = tea.KeyMsg{Type: tea.KeyTab}
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			case "right":
+				msg = tea.KeyMsg{Type: tea.KeyRight}
+			case "backspace":
+				msg = tea.KeyMsg{Type: tea.KeyBackspace}
+			default:
+				msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			}
+			model, _ := mon.Update(msg)
+			mon = model.(Monitor)
+		}
+	}
+
+	press("tab", "tab", "e") // The CPU pane follows the memory pane
+	assert.True(t, mon.regEditing)
+
+	press("4", "z", "2", "3", "enter") // Only two digits fit in A
+	assert.Equal(t, uint8(0x42), c.A)
+
+	press("right", "right", "right", "2", "0", "0", "0", "enter") // PC
+	assert.Equal(t, uint16(0x2000), c.PC)
+	assert.Equal(t, uint16(0x2000), mon.locations[mon.selectedLocation].PC, "editing PC relocates")
+
+	press("right", "right", "right", "enter") // N
+	assert.Equal(t, cpu.FlagN, c.P)
+
+	press("right", "right", "right", "right", "right", "right", "enter") // C
+	assert.Equal(t, cpu.FlagN|cpu.FlagC, c.P)
+
+	press("right", "5", "backspace", "esc") // Wraps to A; abandoned input is discarded
+	assert.False(t, mon.regEditing)
+	assert.Equal(t, uint8(0x42), c.A)
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"github.com/newhook/6502/dis/disassembler"
+)
+
+// maxBackScan bounds how many instructions previousInstruction chains back
+// when scoring a candidate alignment
+const maxBackScan = 8
+
+// previousInstruction finds where the instruction ending at addr starts.
+// Each of the preceding 1-3 bytes is tried as an opcode whose instruction
+// ends exactly at addr; the candidate that chains back through the most
+// consistent decodes wins. With no candidate the previous byte is data.
+func (m *Monitor) previousInstruction(addr uint16) uint16 {
+	// A chain of maxBackScan instructions before a candidate spans at most
+	// 3 bytes each, so only that window is decoded, once per address
+	low := max(int(addr)-3*(maxBackScan+1), 0)
+	sizes := m.instructionSizes(low, int(addr))
+	chains := chainLengths(sizes)
+
+	best, bestScore := addr-1, -1
+	for size := 1; size <= 3 && size <= int(addr)-low; size++ {
+		start := int(addr) - size
+		if sizes[start-low] != size {
+			continue
+		}
+		if score := min(chains[start-low], maxBackScan); score > bestScore {
+			best, bestScore = uint16(start), score
+		}
+	}
+	return best
+}
+
+// instructionSizes returns the size of the valid instruction starting at
+// each address from low up to end, or 0 where the byte is not an opcode
+func (m *Monitor) instructionSizes(low, end int) []int {
+	d := m.newDisassembler()
+	sizes := make([]int, end-low)
+	for i := range sizes {
+		if l := d.One(uint16(low + i)); l.Inst != nil {
+			sizes[i] = l.Size()
+		}
+	}
+	return sizes
+}
+
+// chainLengths returns, for each offset into sizes, how many whole
+// instructions decode back-to-back ending there, counting from the start
+// of sizes
+func chainLengths(sizes []int) []int {
+	chains := make([]int, len(sizes)+1)
+	for i := 1; i < len(chains); i++ {
+		for size := 1; size <= 3 && size <= i; size++ {
+			if sizes[i-size] == size {
+				chains[i] = max(chains[i], 1+chains[i-size])
+			}
+		}
+	}
+	return chains
+}
+
+// scrollUp moves the disassembly selection up by lines instructions,
+// re-deriving each boundary from memory so the view stays aligned after
+// self-modifying code or when the precomputed list decoded data as code.
+func (m *Monitor) scrollUp(lines int) {
+	for i := 0; i < lines && m.selectedLocation > 0; i++ {
+		cur := m.locations[m.selectedLocation].PC
+		prev := m.previousInstruction(cur)
+		if m.locations[m.selectedLocation-1].PC == prev {
+			m.locations[m.selectedLocation-1] = m.newDisassembler().One(prev)
+		} else {
+			m.realign(prev)
+		}
+		m.selectedLocation--
+	}
+}
+
+// realign splices a fresh decode of the instruction at prev in front of the
+// selected location. Earlier entries overlapping it are replaced by single
+// data bytes so the list still covers memory contiguously.
+func (m *Monitor) realign(prev uint16) {
+	before := m.locations[:m.selectedLocation]
+	keep := len(before)
+	for keep > 0 && int(before[keep-1].PC)+before[keep-1].Size() > int(prev) {
+		keep--
+	}
+
+	head := make([]disassembler.Location, 0, keep+4)
+	head = append(head, before[:keep]...)
+	next := 0
+	if keep > 0 {
+		next = int(before[keep-1].PC) + before[keep-1].Size()
+	}
+	for addr := next; addr < int(prev); addr++ {
+		head = append(head, disassembler.Location{PC: uint16(addr), Value: m.mem.Read(uint16(addr))})
+	}
+	head = append(head, m.newDisassembler().One(prev))
+
+	m.locations = append(head, m.locations[m.selectedLocation:]...)
+	m.selectedLocation = len(head)
+}
+
+// refreshMargin is how many locations either side of the disassembly pane
+// refreshDisassembly re-decodes
+const refreshMargin = 32
+
+// refreshDisassembly re-decodes the locations around the disassembly pane
+// from current memory, so pokes and self-modifying code show up without
+// disassembling all 64K again. Decoding continues past the window until it
+// lines up with an existing instruction boundary.
+func (m *Monitor) refreshDisassembly() {
+	selected := m.locations[m.selectedLocation].PC
+	first := m.viewStart() - refreshMargin
+	if first < 0 {
+		first = 0
+	}
+	last := m.viewStart() + disassemblyLines + refreshMargin
+	if last > len(m.locations) {
+		last = len(m.locations)
+	}
+	end := int(m.locations[last-1].PC) + m.locations[last-1].Size()
+
+	d := m.newDisassembler()
+	var fresh []disassembler.Location
+	rest := last
+	for addr := int(m.locations[first].PC); addr <= 0xFFFF; {
+		if addr >= end && (rest == len(m.locations) || int(m.locations[rest].PC) == addr) {
+			break
+		}
+		l := d.One(uint16(addr))
+		fresh = append(fresh, l)
+		addr += l.Size()
+		for rest < len(m.locations) && int(m.locations[rest].PC) < addr {
+			rest++
+		}
+	}
+	m.locations = append(append(m.locations[:first:first], fresh...), m.locations[rest:]...)
+
+	// relocate finds the PC in the new list; keep the user's selection
+	m.relocate()
+	m.showDisassembly(selected)
+}
@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/newhook/6502/cpu"
+	"strconv"
+	"strings"
+)
+
+// WatchKind selects what a Watchpoint compares
+type WatchKind int
+
+const (
+	WatchRead     WatchKind = iota // The CPU reads Addr
+	WatchWrite                     // The CPU writes Addr
+	WatchRegister                  // Register becomes Value
+)
+
+// Watchpoint pauses execution on a data access or register condition
+type Watchpoint struct {
+	Kind     WatchKind
+	Addr     uint16
+	Register string // A, X, Y, SP, P or PC for WatchRegister
+	Value    uint16
+	HasValue bool // Accesses only match when they transfer Value
+}
+
+func (w Watchpoint) String() string {
+	switch w.Kind {
+	case WatchRegister:
+		return fmt.Sprintf("%s=$%02X", w.Register, w.Value)
+	case WatchRead, WatchWrite:
+		kind := "read"
+		if w.Kind == WatchWrite {
+			kind = "write"
+		}
+		if w.HasValue {
+			return fmt.Sprintf("%s $%04X = $%02X", kind, w.Addr, w.Value)
+		}
+		return fmt.Sprintf("%s $%04X", kind, w.Addr)
+	}
+	return "?"
+}
+
+// matchesAccess reports whether a bus access triggers a data watchpoint
+func (w Watchpoint) matchesAccess(addr uint16, value uint8, write bool) bool {
+	if w.Kind == WatchRegister || (w.Kind == WatchWrite) != write || w.Addr != addr {
+		return false
+	}
+	return !w.HasValue || w.Value == uint16(value)
+}
+
+// matchesRegisters reports whether a register watchpoint's condition holds
+func (w Watchpoint) matchesRegisters(c *cpu.CPU) bool {
+	if w.Kind != WatchRegister {
+		return false
+	}
+	return registerValue(c, w.Register) == w.Value
+}
+
+// parseWatchpoint reads the watch dialog's input, all values in hex:
+//
+//	r ADDR [VALUE]   break when ADDR is read (optionally as VALUE)
+//	w ADDR [VALUE]   break when ADDR is written (optionally with VALUE)
+//	REG=VALUE        break when register A, X, Y, SP, P or PC becomes VALUE
+func parseWatchpoint(input string) (Watchpoint, error) {
+	input = strings.TrimSpace(input)
+	if reg, value, ok := strings.Cut(input, "="); ok {
+		reg = strings.ToUpper(strings.TrimSpace(reg))
+		bits := 8
+		switch reg {
+		case "A", "X", "Y", "SP", "P":
+		case "PC":
+			bits = 16
+		default:
+			return Watchpoint{}, fmt.Errorf("unknown register %q", reg)
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(value), 16, bits)
+		if err != nil {
+			return Watchpoint{}, fmt.Errorf("bad %s value %q", reg, strings.TrimSpace(value))
+		}
+		return Watchpoint{Kind: WatchRegister, Register: reg, Value: uint16(v)}, nil
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) < 2 || len(fields) > 3 {
+		return Watchpoint{}, fmt.Errorf("expected r|w ADDR [VALUE] or REG=VALUE")
+	}
+	var w Watchpoint
+	switch strings.ToLower(fields[0]) {
+	case "r":
+		w.Kind = WatchRead
+	case "w":
+		w.Kind = WatchWrite
+	default:
+		return Watchpoint{}, fmt.Errorf("unknown watch kind %q", fields[0])
+	}
+	addr, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return Watchpoint{}, fmt.Errorf("bad address %q", fields[1])
+	}
+	w.Addr = uint16(addr)
+	if len(fields) == 3 {
+		value, err := strconv.ParseUint(fields[2], 16, 8)
+		if err != nil {
+			return Watchpoint{}, fmt.Errorf("bad value %q", fields[2])
+		}
+		w.Value = uint16(value)
+		w.HasValue = true
+	}
+	return w, nil
+}
+
+// watcher checks watchpoints while the CPU steps. It is held by pointer so
+// copies of the Monitor model share it with the bus observers.
+type watcher struct {
+	points []Watchpoint
+	bus    *cpu.ObservedBus
+	armed  bool   // Accesses come from the CPU, not the monitor's own views
+	hit    string // First watchpoint triggered during the current step
+}
+
+// add appends w, routing the CPU's bus through an observer the first time a
+// data watchpoint needs one
+func (w *watcher) add(c *cpu.CPU, point Watchpoint) {
+	w.points = append(w.points, point)
+	if point.Kind == WatchRegister || w.bus != nil {
+		return
+	}
+	w.bus = cpu.NewObservedBus(c.Bus)
+	w.bus.SetReadObserver(func(addr uint16, value uint8) {
+		w.access(addr, value, false)
+	})
+	w.bus.SetWriteObserver(func(addr uint16, old, new uint8) {
+		w.access(addr, new, true)
+	})
+	c.Bus = w.bus
+}
+
+func (w *watcher) access(addr uint16, value uint8, write bool) {
+	if !w.armed || w.hit != "" {
+		return
+	}
+	for _, point := range w.points {
+		if point.matchesAccess(addr, value, write) {
+			w.hit = fmt.Sprintf("watchpoint %s hit ($%02X)", point, value)
+			return
+		}
+	}
+}
+
+// step runs one instruction and returns the watchpoint it triggered, if any.
+// Register watchpoints only trigger when their condition becomes true, so
+// resuming from one does not stop again straight away.
+func (w *watcher) step(c *cpu.CPU, stepper Stepper) string {
+	held := make([]bool, len(w.points))
+	for i, point := range w.points {
+		held[i] = point.matchesRegisters(c)
+	}
+
+	w.armed, w.hit = true, ""
+	stepper.Step()
+	w.armed = false
+
+	if w.hit != "" {
+		return w.hit
+	}
+	for i, point := range w.points {
+		if !held[i] && point.matchesRegisters(c) {
+			return fmt.Sprintf("watchpoint %s hit", point)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"github.com/newhook/6502/cpu"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseWatchpoint(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Watchpoint
+		err   bool
+	}{
+		{input: "w d020", want: Watchpoint{Kind: WatchWrite, Addr: 0xD020}},
+		{input: "W D020 01", want: Watchpoint{Kind: WatchWrite, Addr: 0xD020, Value: 0x01, HasValue: true}},
+		{input: "r fb", want: Watchpoint{Kind: WatchRead, Addr: 0x00FB}},
+		{input: "a=10", want: Watchpoint{Kind: WatchRegister, Register: "A", Value: 0x10}},
+		{input: " pc = C000 ", want: Watchpoint{Kind: WatchRegister, Register: "PC", Value: 0xC000}},
+		{input: "q=1", err: true},
+		{input: "a=100", err: true},
+		{input: "x 1000", err: true},
+		{input: "w", err: true},
+		{input: "w 1000 100", err: true},
+	}
+
+	for _, tt := range tests {
+		w, err := parseWatchpoint(tt.input)
+		if tt.err {
+			assert.Error(t, err, tt.input)
+			continue
+		}
+		if assert.NoError(t, err, tt.input) {
+			assert.Equal(t, tt.want, w, tt.input)
+		}
+	}
+}
+
+func TestWatchpointMatching(t *testing.T) {
+	write := Watchpoint{Kind: WatchWrite, Addr: 0xD020}
+	assert.True(t, write.matchesAccess(0xD020, 0x05, true))
+	assert.False(t, write.matchesAccess(0xD020, 0x05, false), "reads don't match a write watch")
+	assert.False(t, write.matchesAccess(0xD021, 0x05, true))
+
+	value := Watchpoint{Kind: WatchRead, Addr: 0x00FB, Value: 0x80, HasValue: true}
+	assert.True(t, value.matchesAccess(0x00FB, 0x80, false))
+	assert.False(t, value.matchesAccess(0x00FB, 0x7F, false))
+
+	c := cpu.NewCPU(nil)
+	c.X = 0x10
+	c.PC = 0xC000
+	assert.True(t, Watchpoint{Kind: WatchRegister, Register: "X", Value: 0x10}.matchesRegisters(c))
+	assert.False(t, Watchpoint{Kind: WatchRegister, Register: "Y", Value: 0x10}.matchesRegisters(c))
+	assert.True(t, Watchpoint{Kind: WatchRegister, Register: "PC", Value: 0xC000}.matchesRegisters(c))
+	assert.False(t, write.matchesRegisters(c))
+}
+
+func TestWatcherStep(t *testing.T) {
+	c := cpu.NewCPUAndMemory()
+	copy(c.Memory[0x1000:], []uint8{
+		cpu.LDA_IMM, 0x01,
+		cpu.STA_ABS, 0x20, 0xD0, // STA $D020
+		cpu.INX,
+		cpu.LDA_ABS, 0x20, 0xD0, // LDA $D020
+	})
+	c.PC = 0x1000
+
+	w := &watcher{}
+	w.add(&c.CPU, Watchpoint{Kind: WatchWrite, Addr: 0xD020, Value: 0x01, HasValue: true})
+	w.add(&c.CPU, Watchpoint{Kind: WatchRegister, Register: "X", Value: 0x01})
+	w.add(&c.CPU, Watchpoint{Kind: WatchRead, Addr: 0xD020})
+
+	assert.Empty(t, w.step(&c.CPU, c))
+	assert.Equal(t, "watchpoint write $D020 = $01 hit ($01)", w.step(&c.CPU, c))
+	assert.Equal(t, "watchpoint X=$01 hit", w.step(&c.CPU, c))
+	assert.Equal(t, "watchpoint read $D020 hit ($01)", w.step(&c.CPU, c))
+
+	// Reads made outside a step, like the monitor's memory view, are ignored
+	w.hit = ""
+	c.Bus.Read(0xD020)
+	assert.Empty(t, w.hit)
+}
+
+func TestRunStopsAtWatchpoint(t *testing.T) {
+	m, c := newTestMonitor(
+		cpu.INX,
+		cpu.STX_ABS, 0x00, 0x02, // STX $0200
+		cpu.JMP_ABS, 0x00, 0x10, // JMP $1000
+	)
+	m.watch.add(m.cpu, Watchpoint{Kind: WatchWrite, Addr: 0x0200, Value: 0x03, HasValue: true})
+	m.paused = false
+
+	stopped := runUntilPaused(t, *m)
+	assert.Equal(t, uint8(0x03), c.Memory[0x0200])
+	assert.Equal(t, uint16(0x1004), c.PC)
+	assert.Equal(t, "watchpoint write $0200 = $03 hit ($03)", stopped.status)
+}